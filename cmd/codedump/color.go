@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// painter wraps strings in ANSI escapes when color is enabled.
+type painter struct{ on bool }
+
+// newPainter resolves a -color mode (auto, always, never) for the given stream.
+// auto enables color only on a terminal and honors NO_COLOR.
+func newPainter(mode string, f *os.File) (painter, error) {
+	switch mode {
+	case "always":
+		return painter{on: true}, nil
+	case "never":
+		return painter{}, nil
+	case "", "auto":
+		if os.Getenv("NO_COLOR") != "" { return painter{}, nil }
+		return painter{on: isTerminal(f)}, nil
+	}
+	return painter{}, fmt.Errorf("invalid -color %q (want auto, always or never)", mode)
+}
+
+// isTerminal reports whether f is a character device such as a TTY.
+func isTerminal(f *os.File) bool {
+	st, err := f.Stat()
+	return err == nil && st.Mode()&os.ModeCharDevice != 0
+}
+
+func (p painter) wrap(code, s string) string {
+	if !p.on { return s }
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+func (p painter) bold(s string) string { return p.wrap("1", s) }
+func (p painter) dim(s string) string  { return p.wrap("2", s) }
+func (p painter) red(s string) string  { return p.wrap("31", s) }
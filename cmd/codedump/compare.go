@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/devMoisa/tool.codeDump/pkg/codedump"
+)
+
+// compare implements -compare: it reports how the files in dir differ from the
+// dump and exits non-zero on any difference.
+func compare(c codedump.Config, dumpPath, dir string) {
+	results, err := codedump.Compare(dumpPath, dir, c)
+	if err != nil { fatal(err) }
+	counts := map[codedump.CompareStatus]int{}
+	for _, r := range results {
+		counts[r.Status]++
+		if r.Status != codedump.CompareUnchanged {
+			fmt.Printf("%-10s %s\n", r.Status, r.RelPath)
+		}
+	}
+	fmt.Printf("%d unchanged, %d modified, %d deleted, %d added.\n",
+		counts[codedump.CompareUnchanged], counts[codedump.CompareModified], counts[codedump.CompareDeleted], counts[codedump.CompareAdded])
+	if len(results) != counts[codedump.CompareUnchanged] { os.Exit(1) }
+}
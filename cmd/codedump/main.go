@@ -1,32 +1,240 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/devMoisa/tool.codeDump/pkg/codedump"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "restore" {
+		runRestore(os.Args[2:])
+		return
+	}
+
 	var (
-		flInit                      bool
+		flInit, flValidateRC        bool
 		flRoot, flTarget, flOut     string
 		flExt, flInclude, flExclude string
 		flPkg                       bool
-		flRCPath                    string
+		flRCPath, flProfile         string
+		flBranchDiff                bool
+		flBaseBranch                string
+		flSimilarity                float64
+		flDedup                     bool
+		flSkipVendored, flPkgDocs   bool
+		flSummary                   string
+		flTrimComments              int
+		flPaths, flGraph            string
+		flExpect, flStripExts       string
+		flDryRun, flVerbose         bool
+		flTreeOnly                  bool
+		flTreeHash                  bool
+		flWatch                     bool
+		flWatchDebounce             time.Duration
+		flPostURL                   string
+		flPostHeaders               headerList
+		flPostTimeout               time.Duration
+		flFollowEmbeds, flResume    bool
+		flCheckpoint                string
+		flShuffle, flCollapseBlanks bool
+		flSeed                      int64
+		flSort                      string
+		flSortDesc, flSizeGroup     bool
+		flSizeSmall, flSizeLarge    string
+		flDupeReport                string
+		flDupeWindow                int
+		flTodoIndex, flTodoKeywords string
+		flFormat                    string
+		flSkipBuildIgnore           bool
+		flFileSummary               bool
+		flPruneGlob                 string
+		flMaxDepth                  int
+		flWithTestdata              bool
+		flNoTestdata                bool
+		flObjectStore               string
+		flWarnLineLength            int
+		flSkipLongLines             bool
+		flStrip                     string
+		flStripComments             bool
+		flRelativizePaths           bool
+		flGroupBy                   string
+		flOnePerDir                 bool
+		flCoverProfile              string
+		flAnnotateCoverage          bool
+		flChatDelimiter             string
+		flChatPreamble              string
+		flSkipSymlinks              bool
+		flFollowSymlinks            bool
+		flOlderThan, flNewerThan    string
+		flGitAge                    bool
+		flRelativeAge               bool
+		flGrep                      string
+		flGrepContext               int
+		flGitIgnore                 bool
+		flIgnoreFile                string
+		flBlameFiles                string
+		flSkipEmpty                 bool
+		flRequireUTF8               bool
+		flFilters                   string
+		flBinaryPreview             int
+		flFiles                     string
+		flOnInvalid                 string
+		flMaxFileSize               string
+		flMaxDirFiles               int
+		flChunkHashes               int
+		flCompressionStats          bool
+		flMaxTokens                 int
+		flPackageReadmes            bool
+		flTree                      bool
+		flPackagesOverview          bool
+		flModGraph                  bool
+		flCheckGoVersion            bool
+		flStrictGoVersion           bool
+		flReplace                   listFlag
+		flConcurrency               int
+		flFailFast                  bool
+		flModelsSummary             bool
+		flDocsOnly, flDocsSkipOther bool
+		flTimestampOut              bool
+		flStdout                    bool
+		flTimestampLayout           string
+		flParts                     int
+		flMaxOutputBytes            string
+		flBalanceParts              bool
+		flCompare, flCompareDir     string
+		flUnpack, flVerify          string
+		flMerge                     bool
+		flMergeConflict             string
+		flColor, flHashTree         string
+		flRelTo, flFuncs, flUses    string
 	)
 
 	flag.BoolVar(&flInit, "init", false, fmt.Sprintf("Create a %s in the current directory", codedump.DefaultRCName))
-	flag.StringVar(&flRCPath, "rc", "", "Path to RC file (optional). If empty, will search locally and in $HOME")
+	flag.BoolVar(&flValidateRC, "validate-rc", false, "Check an RC file (argument, -rc, or the one found) for unknown keys and bad values, then exit")
+	flag.StringVar(&flRCPath, "rc", "", "Path to RC or YAML config file (optional). If empty, will search locally and in $HOME")
+	flag.StringVar(&flProfile, "profile", "", "RC profile section ([name]) whose keys override the base config")
 	flag.StringVar(&flRoot, "root", "", "Root dir (overrides RC)")
-	flag.StringVar(&flTarget, "target", "", "Target dir to scan (overrides RC)")
-	flag.StringVar(&flOut, "out", "", "Output file name (overrides RC)")
-	flag.StringVar(&flExt, "ext", "", "Target file extension (overrides RC)")
-	flag.StringVar(&flInclude, "include", "", "Required substring in path (overrides RC)")
+	flag.StringVar(&flTarget, "target", "", "Target dir to scan, or several separated by commas (overrides RC)")
+	flag.StringVar(&flOut, "out", "", "Output file name, or - for stdout (overrides RC)")
+	flag.BoolVar(&flStdout, "stdout", false, "Write the dump to stdout; same as -out - (overrides RC)")
+	flag.StringVar(&flExt, "ext", "", "Target file extensions, comma separated (overrides RC)")
+	flag.StringVar(&flInclude, "include", "", "Required substrings in path, comma separated (overrides RC)")
 	flag.StringVar(&flExclude, "exclude", "", "Comma-separated substrings to skip (overrides RC)")
 	flag.BoolVar(&flPkg, "pkg", false, "Preserve package line (overrides RC -> true)")
+	flag.StringVar(&flFormat, "format", "", fmt.Sprintf("Output format, one of %v (default %s; overrides RC)", codedump.Formats(), codedump.DefaultFormat))
+	flag.BoolVar(&flBranchDiff, "branch-diff", false, "Only files changed since the merge base with the default branch (overrides RC -> true)")
+	flag.StringVar(&flBaseBranch, "base-branch", "", "Branch to diff against for -branch-diff (default: origin/HEAD, main or master)")
+	flag.Float64Var(&flSimilarity, "similarity-dedupe", 0, "Collapse files at least this similar (0..1, e.g. 0.95) to an earlier file; approximate (overrides RC)")
+	flag.BoolVar(&flDedup, "dedup", false, "Emit byte-identical files once; later copies reference the first by #duplicate_of (overrides RC -> true)")
+	flag.BoolVar(&flSkipVendored, "skip-vendored", false, "Skip vendor/ dirs and the Go module cache regardless of exclude (overrides RC -> true)")
+	flag.StringVar(&flSummary, "summary", "", "Also write a JSON summary (files, bytes, per-extension and skip counts) to this path (overrides RC)")
+	flag.IntVar(&flTrimComments, "trim-comments-to", 0, "Shorten leading comment blocks (license headers) to N lines (overrides RC)")
+	flag.StringVar(&flPaths, "paths", "", "Base for recorded paths: cwd or relative-to-out (overrides RC)")
+	flag.StringVar(&flRelTo, "rel-to", "", "Shorthand for -paths: cwd, out or git (git repo root)")
+	flag.StringVar(&flGraph, "graph", "", "Also write a Graphviz DOT graph of Go package imports to this path (overrides RC)")
+	flag.StringVar(&flExpect, "expect", "", `Fail unless per-extension counts hold, e.g. "go>=1,proto>=1" (overrides RC)`)
+	flag.StringVar(&flStripExts, "strip-exts", "", "Comma-separated extensions package stripping applies to (default .go; overrides RC)")
+	flag.BoolVar(&flPkgDocs, "package-docs", false, "Emit each Go package's doc comment before its files (overrides RC -> true)")
+	flag.StringVar(&flHashTree, "hash-tree", "", "Also write a Merkle hash tree of the dumped files as JSON to this path (overrides RC)")
+	flag.BoolVar(&flFollowEmbeds, "follow-embeds", false, "Also include files referenced by //go:embed in collected Go files (overrides RC -> true)")
+	flag.StringVar(&flFuncs, "funcs", "", `Go files keep only the package clause and funcs matching these globs, e.g. "ServeHTTP,Handle*" (overrides RC)`)
+	flag.StringVar(&flUses, "uses", "", `Only files importing this package or referencing this symbol, e.g. "net/http" or "http.Handler" (overrides RC)`)
+	flag.StringVar(&flCheckpoint, "checkpoint", "", "Stream the dump and record progress in this file so an interrupted run can be resumed (overrides RC)")
+	flag.BoolVar(&flResume, "resume", false, "Continue an interrupted run from -checkpoint, skipping files already written")
+	flag.BoolVar(&flShuffle, "shuffle", false, "Emit files in a reproducible pseudo-random order (see -seed; overrides RC -> true)")
+	flag.Int64Var(&flSeed, "seed", 0, "Seed for -shuffle (overrides RC)")
+	flag.StringVar(&flSort, "sort", "", "File order: path (default), size, mtime or sha (overrides RC)")
+	flag.BoolVar(&flSortDesc, "sort-desc", false, "Reverse -sort, e.g. biggest or newest files first (overrides RC -> true)")
+	flag.BoolVar(&flSizeGroup, "size-group", false, "With -sort size, emit small, medium and large files in sections, by path within each (overrides RC -> true)")
+	flag.StringVar(&flSizeSmall, "size-group-small", "", "-size-group files below this size are small (default 4KB; overrides RC)")
+	flag.StringVar(&flSizeLarge, "size-group-large", "", "-size-group files of at least this size are large (default 32KB; overrides RC)")
+	flag.StringVar(&flDupeReport, "dupe-report", "", "Also write a JSON report of duplicated multi-line blocks to this path (overrides RC)")
+	flag.IntVar(&flDupeWindow, "dupe-window", 0, "Lines per block for -dupe-report (default 6; overrides RC)")
+	flag.StringVar(&flTodoIndex, "todo-index", "", "Also write a sorted index of TODO/FIXME/XXX/HACK markers to this path (overrides RC)")
+	flag.StringVar(&flTodoKeywords, "todo-keywords", "", "Comma-separated markers for -todo-index (default TODO,FIXME,XXX,HACK; overrides RC)")
+	flag.BoolVar(&flCollapseBlanks, "collapse-blanks", false, "Collapse runs of blank lines to a single one (overrides RC -> true)")
+	flag.BoolVar(&flSkipBuildIgnore, "skip-build-ignore", false, "Skip Go files excluded with //go:build ignore (overrides RC -> true)")
+	flag.BoolVar(&flFileSummary, "file-summary", false, "Add a #summary: line per file from its first doc comment sentence (overrides RC -> true)")
+	flag.StringVar(&flPruneGlob, "prune-glob", "", "Comma-separated directory name globs to never descend into, e.g. \"node_modules,*.cache\" (overrides RC)")
+	flag.BoolVar(&flWithTestdata, "with-testdata", false, "Include files under testdata/ directories (overrides RC -> true)")
+	flag.BoolVar(&flNoTestdata, "no-testdata", false, "Leave out testdata/ directories, the default (overrides RC -> false)")
+	flag.IntVar(&flMaxDepth, "max-depth", 0, "Only dump files at most N directory levels below the target; 1 = its own files (overrides RC)")
+	flag.StringVar(&flObjectStore, "object-store", "", "Write file contents to a content-addressed store in this dir (overrides RC)")
+	flag.IntVar(&flWarnLineLength, "warn-line-length", 0, "Flag files with a line longer than N characters with #has_long_lines (overrides RC)")
+	flag.BoolVar(&flSkipLongLines, "skip-long-lines", false, "With -warn-line-length, skip such files instead of flagging them (overrides RC -> true)")
+	flag.StringVar(&flStrip, "strip", "", "Comma-separated content to strip from files: comments (overrides RC)")
+	flag.BoolVar(&flStripComments, "strip-comments", false, "Remove comments from .go files using the Go scanner (overrides RC -> true)")
+	flag.BoolVar(&flRelativizePaths, "relativize-paths", false, "Rewrite the absolute root path inside file content to ${ROOT} (overrides RC -> true)")
+	flag.StringVar(&flGroupBy, "group-by", "", "Group files into sections: owner (from CODEOWNERS) (overrides RC)")
+	flag.BoolVar(&flOnePerDir, "one-per-dir", false, "Keep only the alphabetically first file of each directory (overrides RC -> true)")
+	flag.StringVar(&flCoverProfile, "coverprofile", "", "Coverage profile from go test -coverprofile, for -annotate-coverage (overrides RC)")
+	flag.BoolVar(&flAnnotateCoverage, "annotate-coverage", false, "Prefix uncovered statement lines of .go files with ! (overrides RC -> true)")
+	flag.StringVar(&flChatDelimiter, "chat-delimiter", "", "File delimiters for -format chat: xml or markdown (overrides RC)")
+	flag.StringVar(&flChatPreamble, "chat-preamble", "", "System message for -format chat (overrides RC)")
+	flag.BoolVar(&flSkipSymlinks, "skip-symlinks", true, "Skip symlinked files; use -skip-symlinks=false to read them (overrides RC)")
+	flag.BoolVar(&flFollowSymlinks, "follow-symlinks", false, "Walk into symlinked directories, skipping links back to an ancestor (overrides RC -> true)")
+	flag.StringVar(&flOlderThan, "older-than", "", "Keep only files at least this old, e.g. 30d, 2w, 12h (overrides RC)")
+	flag.StringVar(&flNewerThan, "newer-than", "", "Keep only files at most this old, e.g. 30d, 2w, 12h (overrides RC)")
+	flag.BoolVar(&flGitAge, "git-age", false, "Judge -older-than/-newer-than by last commit date instead of mtime (overrides RC -> true)")
+	flag.BoolVar(&flRelativeAge, "relative-age", false, "Record each file's #mod_time and its #age at generation time, e.g. 3d4h (overrides RC -> true)")
+	flag.StringVar(&flGrep, "grep", "", "Keep only files whose content matches this regexp (overrides RC)")
+	flag.IntVar(&flGrepContext, "grep-context", -1, "With -grep, emit only matching lines plus N lines of context (overrides RC)")
+	flag.BoolVar(&flGitIgnore, "gitignore", false, "Skip paths ignored by .gitignore files (overrides RC -> true)")
+	flag.StringVar(&flIgnoreFile, "ignore-file", "", "Gitignore-syntax file at the target root whose patterns are excluded (default .codedumpignore; overrides RC)")
+	flag.StringVar(&flBlameFiles, "blame-files", "", "Comma-separated globs on #rel_path; prefix lines of matching files with git blame commit and author (overrides RC)")
+	flag.IntVar(&flConcurrency, "concurrency", 0, "Files read and hashed at once (default: number of CPUs) (overrides RC)")
+	flag.BoolVar(&flFailFast, "fail-fast", false, "Stop at the first file that cannot be read instead of dumping the others (overrides RC -> true)")
+	flag.BoolVar(&flModelsSummary, "models-summary", false, "Reduce .go files to their struct definitions, fields and tags (overrides RC -> true)")
+	flag.BoolVar(&flDocsOnly, "docs-only", false, "Reduce .go files to their doc comments and declaration signatures (overrides RC -> true)")
+	flag.BoolVar(&flDocsSkipOther, "docs-skip-other", false, "With -docs-only, leave out non-.go files instead of keeping them whole (overrides RC -> true)")
+	flag.BoolVar(&flTimestampOut, "timestamp-out", false, "Insert the generation time into the output file name (overrides RC -> true)")
+	flag.StringVar(&flTimestampLayout, "timestamp-layout", "", "Go time layout for -timestamp-out (default 20060102T150405) (overrides RC)")
+	flag.Var(&flReplace, "replace", "Comma-separated old=new substitutions applied to file content, e.g. \"oldco.com=example.com\" (repeatable; overrides RC)")
+	flag.BoolVar(&flPackageReadmes, "package-readmes", false, "Emit each directory's README.md before its files (overrides RC -> true)")
+	flag.BoolVar(&flTree, "tree", false, "Open the dump with an ASCII tree of the dumped files, like the tree command (overrides RC -> true)")
+	flag.BoolVar(&flPackagesOverview, "packages-overview", false, "Open the dump with a table of the Go packages: import path, files, lines (overrides RC -> true)")
+	flag.BoolVar(&flModGraph, "mod-graph", false, "Open the dump with the direct and indirect dependencies of the target's go.mod, with versions (overrides RC -> true)")
+	flag.BoolVar(&flCheckGoVersion, "check-go-version", false, "Record whether the go directive of the target's go.mod matches the running Go as #go_version_match (overrides RC -> true)")
+	flag.BoolVar(&flStrictGoVersion, "strict-go-version", false, "Like -check-go-version, but fail when they do not match (overrides RC -> true)")
+	flag.IntVar(&flMaxTokens, "max-tokens", 0, "Stop adding files once their estimated tokens would exceed N (overrides RC)")
+	flag.IntVar(&flChunkHashes, "chunk-hashes", 0, "Record the sha256 of every N-line chunk of each file as #chunk_sha256 (overrides RC)")
+	flag.BoolVar(&flCompressionStats, "compression-stats", false, "Record each file's gzip-compressed size as #compressed_bytes (overrides RC -> true)")
+	flag.IntVar(&flMaxDirFiles, "max-dir-files", 0, "Skip directories directly holding more than N matching files (overrides RC)")
+	flag.StringVar(&flMaxFileSize, "max-file-size", "", "Skip files larger than this, e.g. 5MB or 512KB (overrides RC)")
+	flag.BoolVar(&flSkipEmpty, "skip-empty", false, "Skip zero-byte files and report how many were left out (overrides RC -> true)")
+	flag.StringVar(&flFiles, "files", "", "Comma-separated files to dump, relative to target, in this order instead of walking it (overrides RC)")
+	flag.IntVar(&flBinaryPreview, "binary-preview", 0, "Replace binary files' content with a hex dump of their first N bytes (overrides RC)")
+	flag.StringVar(&flFilters, "filters", "", "Comma-separated registered filters to apply in order, e.g. no-tests,no-binary (overrides RC)")
+	flag.BoolVar(&flRequireUTF8, "require-utf8", false, "Reject files that are not valid UTF-8, as -on-invalid says (overrides RC -> true)")
+	flag.StringVar(&flOnInvalid, "on-invalid", "", "With -require-utf8: error (abort with the path, default) or skip (overrides RC)")
+	flag.IntVar(&flParts, "parts", 0, "Split the output into N files named <out>.partK.<ext> (overrides RC)")
+	flag.StringVar(&flMaxOutputBytes, "max-output-bytes", "", "Split the output into <out>.001.<ext>, .002, ... of at most this size, e.g. 2MB (overrides RC)")
+	flag.BoolVar(&flBalanceParts, "balance-parts", false, "With -parts, pack files largest-first to even out part sizes (overrides RC -> true)")
+	flag.StringVar(&flCompare, "compare", "", "Compare this dump with the files on disk and exit non-zero on any difference")
+	flag.StringVar(&flCompareDir, "dir", "", "Directory the -compare dump's paths are relative to, or -unpack writes into (default: as restore picks)")
+	flag.StringVar(&flVerify, "verify", "", "Check every block of this dump against its recorded hash and exit non-zero on any mismatch")
+	flag.StringVar(&flUnpack, "unpack", "", "Recreate the files of this dump under -dir, warning on sha256 mismatches")
+	flag.BoolVar(&flMerge, "merge", false, "Merge the dumps given as arguments into -out, collapsing identical blocks: codedump -merge a.txt b.txt -out all.txt")
+	flag.StringVar(&flMergeConflict, "merge-conflict", codedump.ConflictError, "With -merge, when dumps record one path with different hashes: error or suffix (keep both, renaming the later one)")
+	flag.BoolVar(&flDryRun, "dry-run", false, "List the files that would be dumped, grouped by directory, without writing anything")
+	flag.BoolVar(&flTreeOnly, "tree-only", false, "Print the tree of files that would be dumped, without reading or hashing them")
+	flag.BoolVar(&flTreeHash, "tree-hash", false, "Print one sha256 of the paths and contents of the files that would be dumped, e.g. as a CI cache key, without writing anything")
+	flag.BoolVar(&flWatch, "watch", false, "Regenerate the dump whenever the dumped files change, until interrupted")
+	flag.DurationVar(&flWatchDebounce, "watch-debounce", 500*time.Millisecond, "With -watch, wait for this long without changes before regenerating")
+	flag.StringVar(&flPostURL, "post-url", "", "POST the dump in json format to this URL instead of writing a file")
+	flag.Var(&flPostHeaders, "post-header", "Extra \"Name: value\" header for -post-url, e.g. for auth (repeatable)")
+	flag.DurationVar(&flPostTimeout, "post-timeout", 30*time.Second, "Timeout for the -post-url request")
+	flag.BoolVar(&flVerbose, "verbose", false, "Report every skipped file or directory and why on stderr")
+	flag.StringVar(&flColor, "color", "auto", "Colorize -dry-run and -verbose output: auto, always or never")
 	flag.Parse()
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
 
 	if flInit {
 		if err := codedump.WriteDefaultRC(codedump.DefaultRCName); err != nil {
@@ -36,28 +244,265 @@ func main() {
 		return
 	}
 
+	if flValidateRC {
+		validateRC(flRCPath)
+		return
+	}
+
+	if flMerge {
+		inputs := interspersedArgs()
+		if flStdout { flOut = codedump.StdoutOut }
+		merge(inputs, flOut, flMergeConflict)
+		return
+	}
+
 	c := codedump.DefaultConfig()
+	c.Warnf = func(format string, args ...any) {
+		fmt.Fprintf(os.Stderr, "⚠️  warning: "+format+"\n", args...)
+	}
 	rcPath := flRCPath
 	if rcPath == "" {
 		rcPath = codedump.FindRC()
 	}
+	if rcPath == "" && flProfile != "" {
+		fatal(fmt.Errorf("profile %q requested but no %s found", flProfile, codedump.DefaultRCName))
+	}
 	if rcPath != "" {
-		if err := codedump.ReadRC(rcPath, &c); err != nil {
+		if err := codedump.ReadRCProfile(rcPath, flProfile, &c); err != nil {
 			fatal(fmt.Errorf("error reading RC %s: %w", rcPath, err))
 		}
+		c.RCPath = rcPath
 	}
 
 	if flRoot != "" { c.Root = flRoot }
 	if flTarget != "" { c.Target = flTarget }
 	if flOut != "" { c.Out = flOut }
+	if flStdout { c.Out = codedump.StdoutOut }
 	if flExt != "" { c.Ext = flExt }
 	if flInclude != "" { c.Include = flInclude }
 	if flExclude != "" { c.Exclude = flExclude }
 	if flPkg { c.Pkg = true }
+	if flFormat != "" { c.Format = flFormat }
+	if flStripExts != "" { c.StripExts = flStripExts }
+	if flBranchDiff { c.BranchDiff = true }
+	if flBaseBranch != "" { c.BaseBranch = flBaseBranch }
+	if flSimilarity > 0 { c.SimilarityDedupe = flSimilarity }
+	if flDedup { c.Dedup = true }
+	if flSkipVendored { c.SkipVendored = true }
+	if flSummary != "" { c.Summary = flSummary }
+	if flTrimComments > 0 { c.TrimCommentsTo = flTrimComments }
+	if flPaths != "" { c.Paths = flPaths }
+	switch flRelTo {
+	case "":
+	case "cwd": c.Paths = codedump.PathsCWD
+	default: c.Paths = "relative-to-" + flRelTo
+	}
+	if flGraph != "" { c.Graph = flGraph }
+	if flExpect != "" { c.Expect = flExpect }
+	if flPkgDocs { c.PackageDocs = true }
+	if flHashTree != "" { c.HashTree = flHashTree }
+	if flFollowEmbeds { c.FollowEmbeds = true }
+	if flFuncs != "" { c.Funcs = flFuncs }
+	if flUses != "" { c.Uses = flUses }
+	if flCheckpoint != "" { c.Checkpoint = flCheckpoint }
+	if flResume { c.Resume = true }
+	if flShuffle { c.Shuffle = true }
+	if flSeed != 0 { c.Seed = flSeed }
+	if flSort != "" { c.Sort = flSort }
+	if flSortDesc { c.SortDesc = true }
+	if flSizeGroup { c.SizeGroup = true }
+	if flSizeSmall != "" {
+		n, err := codedump.ParseSize(flSizeSmall)
+		if err != nil { fatal(fmt.Errorf("-size-group-small: %w", err)) }
+		c.SizeGroupSmall = n
+	}
+	if flSizeLarge != "" {
+		n, err := codedump.ParseSize(flSizeLarge)
+		if err != nil { fatal(fmt.Errorf("-size-group-large: %w", err)) }
+		c.SizeGroupLarge = n
+	}
+	if flDupeReport != "" { c.DupeReport = flDupeReport }
+	if flDupeWindow > 0 { c.DupeWindow = flDupeWindow }
+	if flTodoIndex != "" { c.TodoIndex = flTodoIndex }
+	if flCollapseBlanks { c.CollapseBlanks = true }
+	if flSkipBuildIgnore { c.SkipBuildIgnore = true }
+	if flFileSummary { c.FileSummary = true }
+	if flPruneGlob != "" { c.PruneGlob = flPruneGlob }
+	if flMaxDepth > 0 { c.MaxDepth = flMaxDepth }
+	if flWithTestdata { c.WithTestdata = true }
+	if flNoTestdata { c.WithTestdata = false }
+	if flObjectStore != "" { c.ObjectStore = flObjectStore }
+	if flWarnLineLength > 0 { c.WarnLineLength = flWarnLineLength }
+	if flSkipLongLines { c.SkipLongLines = true }
+	if flStrip != "" { c.Strip = flStrip }
+	if flStripComments { c.StripComments = true }
+	if flRelativizePaths { c.RelativizePaths = true }
+	if flGroupBy != "" { c.GroupBy = flGroupBy }
+	if flOnePerDir { c.OnePerDir = true }
+	if flCoverProfile != "" { c.CoverProfile = flCoverProfile }
+	if flAnnotateCoverage { c.AnnotateCoverage = true }
+	if flChatDelimiter != "" { c.ChatDelimiter = flChatDelimiter }
+	if flChatPreamble != "" { c.ChatPreamble = flChatPreamble }
+	if set["skip-symlinks"] { c.SkipSymlinks = flSkipSymlinks }
+	if flFollowSymlinks { c.FollowSymlinks = true }
+	if flOlderThan != "" { c.OlderThan = flOlderThan }
+	if flNewerThan != "" { c.NewerThan = flNewerThan }
+	if flGitAge { c.GitAge = true }
+	if flRelativeAge { c.RelativeAge = true }
+	if flGrep != "" { c.Grep = flGrep }
+	if flGrepContext >= 0 { c.GrepContext = flGrepContext }
+	if flGitIgnore { c.GitIgnore = true }
+	if flIgnoreFile != "" { c.IgnoreFile = flIgnoreFile }
+	if flBlameFiles != "" { c.BlameFiles = flBlameFiles }
+	if flConcurrency > 0 { c.Concurrency = flConcurrency }
+	if flFailFast { c.FailFast = true }
+	if flModelsSummary { c.ModelsSummary = true }
+	if flDocsOnly { c.DocsOnly = true }
+	if flDocsSkipOther { c.DocsSkipOther = true }
+	if flTimestampOut { c.TimestampOut = true }
+	if flTimestampLayout != "" { c.TimestampLayout = flTimestampLayout }
+	if flSkipEmpty { c.SkipEmpty = true }
+	if flFiles != "" { c.Files = flFiles }
+	if flFilters != "" { c.Filters = flFilters }
+	if flBinaryPreview > 0 { c.BinaryPreview = flBinaryPreview }
+	if flRequireUTF8 { c.RequireUTF8 = true }
+	if flOnInvalid != "" { c.OnInvalid = flOnInvalid }
+	if len(flReplace) > 0 { c.Replace = strings.Join(flReplace, ",") }
+	if flPackageReadmes { c.PackageReadmes = true }
+	if flTree { c.Tree = true }
+	if flPackagesOverview { c.PackagesOverview = true }
+	if flModGraph { c.ModGraph = true }
+	if flCheckGoVersion { c.CheckGoVersion = true }
+	if flStrictGoVersion { c.StrictGoVersion = true }
+	if flMaxTokens > 0 { c.MaxTokens = flMaxTokens }
+	if flChunkHashes > 0 { c.ChunkHashes = flChunkHashes }
+	if flCompressionStats { c.CompressionStats = true }
+	if flMaxDirFiles > 0 { c.MaxDirFiles = flMaxDirFiles }
+	if flMaxFileSize != "" {
+		n, err := codedump.ParseSize(flMaxFileSize)
+		if err != nil { fatal(fmt.Errorf("-max-file-size: %w", err)) }
+		c.MaxFileSize = n
+	}
+	if flParts > 0 { c.Parts = flParts }
+	if flBalanceParts { c.BalanceParts = true }
+	if flMaxOutputBytes != "" {
+		n, err := codedump.ParseSize(flMaxOutputBytes)
+		if err != nil { fatal(fmt.Errorf("-max-output-bytes: %w", err)) }
+		c.MaxOutputBytes = n
+	}
+	if flTodoKeywords != "" { c.TodoKeywords = flTodoKeywords }
 
+	out, err := newPainter(flColor, os.Stdout)
+	if err != nil { fatal(err) }
+	// Status lines go to stderr when the dump itself is written to stdout.
+	status := os.Stdout
+	if c.Out == codedump.StdoutOut { status = os.Stderr }
+	if flVerbose {
+		errp, _ := newPainter(flColor, os.Stderr)
+		c.OnSkip = func(path, reason string) {
+			fmt.Fprintf(os.Stderr, "%s %s\n", errp.red("skip ("+reason+")"), path)
+		}
+		c.Notef = func(format string, args ...any) {
+			fmt.Fprintf(os.Stderr, "%s "+format+"\n", append([]any{errp.dim("note:")}, args...)...)
+		}
+	}
+	if c.SkipEmpty {
+		empty, prev := 0, c.OnSkip
+		c.OnSkip = func(path, reason string) {
+			if reason == "empty" { empty++ }
+			if prev != nil { prev(path, reason) }
+		}
+		defer func() {
+			if empty > 0 { fmt.Fprintf(status, "   %d empty files skipped.\n", empty) }
+		}()
+	}
+	if flVerify != "" {
+		verify(flVerify)
+		return
+	}
+	if flUnpack != "" {
+		unpack(flUnpack, flCompareDir)
+		return
+	}
+	if flCompare != "" {
+		compare(c, flCompare, flCompareDir)
+		return
+	}
+	if flDryRun {
+		dryRun(c, out)
+		return
+	}
+	if flTreeOnly {
+		treeOnly(c, out)
+		return
+	}
+	if flTreeHash {
+		treeHash(c)
+		return
+	}
+
+	if flPostURL != "" {
+		post(c, flPostURL, flPostHeaders, flPostTimeout, status)
+		return
+	}
+	if flWatch {
+		if c.SplitsOutput() || c.Out == codedump.StdoutOut { fatal(fmt.Errorf("-watch needs a single output file")) }
+		watch(c, flWatchDebounce, status)
+		return
+	}
+	if c.SplitsOutput() {
+		parts, n, err := codedump.DumpParts(c)
+		warnUnreadable(err)
+		fmt.Printf("✅ codeDump complete! Generated %d parts with %d files.\n", len(parts), n)
+		for _, p := range parts {
+			fmt.Printf("   %s (%d files, %d bytes)\n", p.Path, p.Files, p.Bytes)
+		}
+		return
+	}
 	outAbs, n, err := codedump.Dump(c)
+	warnUnreadable(err)
+	if outAbs == codedump.StdoutOut {
+		fmt.Fprintf(status, "✅ codeDump complete! Wrote %d files to stdout.\n", n)
+		return
+	}
+	fmt.Fprintf(status, "✅ codeDump complete! Generated %q with %d files.\n", outAbs, n)
+}
+
+// listFlag collects the values of a repeatable flag.
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// validateRC implements -validate-rc: it reports every problem in the RC file
+// and exits non-zero if there are any.
+func validateRC(rcPath string) {
+	if flag.NArg() > 0 { rcPath = flag.Arg(0) }
+	if rcPath == "" { rcPath = codedump.FindRC() }
+	if rcPath == "" { fatal(fmt.Errorf("no %s or %s found", codedump.DefaultRCName, codedump.DefaultYAMLName)) }
+	probs, err := codedump.ValidateRC(rcPath)
 	if err != nil { fatal(err) }
-	fmt.Printf("✅ codeDump complete! Generated %q with %d files.\n", outAbs, n)
+	for _, p := range probs {
+		fmt.Fprintf(os.Stderr, "%s:%d: %s\n", rcPath, p.Line, p.Msg)
+	}
+	if len(probs) > 0 {
+		fatal(fmt.Errorf("%s has %d problem(s)", rcPath, len(probs)))
+	}
+	fmt.Printf("✅ %s is valid.\n", rcPath)
+}
+
+// warnUnreadable reports files the run could not read as a warning, leaving
+// the rest of it to finish, and any other error fatally.
+func warnUnreadable(err error) {
+	if errors.Is(err, codedump.ErrUnreadable) {
+		fmt.Fprintf(os.Stderr, "⚠️  warning: %v\n", err)
+	} else if err != nil {
+		fatal(err)
+	}
 }
 
 func fatal(err error) {
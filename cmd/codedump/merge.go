@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/devMoisa/tool.codeDump/pkg/codedump"
+)
+
+// merge implements -merge: it combines the dumps in inputs into one written to
+// out ("-" = stdout), reporting the blocks renamed on conflict.
+func merge(inputs []string, out, onConflict string) {
+	if len(inputs) < 2 { fatal(errors.New("-merge: want two or more dumps to merge")) }
+	if out == "" { fatal(errors.New("-merge: set -out to the merged dump's path, or - for stdout")) }
+	var buf bytes.Buffer
+	res, err := codedump.MergeDumps(&buf, inputs, codedump.MergeOptions{OnConflict: onConflict})
+	if err != nil { fatal(err) }
+	renamed := make([]string, 0, len(res.Renamed))
+	for rel := range res.Renamed {
+		renamed = append(renamed, rel)
+	}
+	sort.Strings(renamed)
+	for _, rel := range renamed {
+		fmt.Fprintf(os.Stderr, "⚠️  warning: %s differs between dumps, kept the later one as %s\n", res.Renamed[rel], rel)
+	}
+
+	status := os.Stdout
+	if out == codedump.StdoutOut {
+		status = os.Stderr
+		if _, err := os.Stdout.Write(buf.Bytes()); err != nil { fatal(err) }
+	} else if err := os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+		fatal(err)
+	}
+	fmt.Fprintf(status, "✅ Merged %d dumps: %d files, %d identical blocks collapsed, %d renamed.\n", len(inputs), res.Files, res.Collapsed, len(renamed))
+}
+
+// interspersedArgs returns the non-flag arguments, parsing the flags between
+// and after them too, so "-merge a.txt b.txt -out all.txt" still sets -out.
+func interspersedArgs() []string {
+	var args []string
+	for flag.NArg() > 0 {
+		args = append(args, flag.Arg(0))
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
+	return args
+}
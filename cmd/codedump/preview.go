@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/devMoisa/tool.codeDump/pkg/codedump"
+)
+
+// dryRun lists the files a dump would include, grouped by directory, without
+// writing anything.
+func dryRun(c codedump.Config, p painter) {
+	wd, _ := os.Getwd()
+	items, err := codedump.CollectTargets(codedump.TargetDirs(wd, c), c)
+	warnUnreadable(err)
+
+	var total int64
+	lastDir := ""
+	for _, it := range items {
+		dir, name := path.Split(it.Rel())
+		if dir == "" { dir = "./" }
+		if dir != lastDir {
+			fmt.Println(p.bold(dir))
+			lastDir = dir
+		}
+		fmt.Printf("  %s %s\n", name, p.dim(humanBytes(it.Size())))
+		total += it.Size()
+	}
+	outAbs := codedump.AbsFrom(codedump.AbsFrom(wd, c.Root), c.Out)
+	fmt.Printf("%d files, %s would be written to %q\n", len(items), humanBytes(total), outAbs)
+}
+
+// treeOnly prints the files a dump would include as a tree. Unlike dryRun it
+// does not hash anything, so it stays fast on big repositories.
+func treeOnly(c codedump.Config, p painter) {
+	wd, _ := os.Getwd()
+	items, err := codedump.CollectTargetPaths(codedump.TargetDirs(wd, c), c)
+	warnUnreadable(err)
+	fmt.Print(codedump.RenderTree(items))
+	var total int64
+	for _, it := range items {
+		total += it.Size()
+	}
+	fmt.Println(p.dim(fmt.Sprintf("%d files, %s", len(items), humanBytes(total))))
+}
+
+// humanBytes formats a byte count for display.
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit { return fmt.Sprintf("%d B", n) }
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// treeHash prints the TreeHash of the files a dump would include. A file that
+// cannot be read would silently change the key, so it is an error here.
+func treeHash(c codedump.Config) {
+	wd, _ := os.Getwd()
+	c.FailFast = true
+	items, err := codedump.CollectTargets(codedump.TargetDirs(wd, c), c)
+	if err != nil { fatal(err) }
+	fmt.Println(codedump.TreeHash(items))
+}
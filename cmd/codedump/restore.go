@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/devMoisa/tool.codeDump/pkg/codedump"
+)
+
+// runRestore implements "codedump restore [flags] <dump.txt>".
+func runRestore(args []string) {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	var (
+		flDest        string
+		flDryRun      bool
+		flOnMismatch  string
+		flAllowParent bool
+	)
+	fs.StringVar(&flDest, "dest", "", "Directory to restore files into (default: the dump's directory for paths=relative-to-out dumps, else the current directory)")
+	fs.BoolVar(&flDryRun, "dry-run", false, "Report new/changed/identical per file without writing anything")
+	fs.StringVar(&flOnMismatch, "on-mismatch", codedump.MismatchError, "When content does not match its #sha256: error, warn (restore anyway) or skip")
+	fs.BoolVar(&flAllowParent, "allow-parent", false, "Also write files whose path climbs out of the destination with ../ (paths=relative-to-out dumps); only for dumps you trust")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: codedump restore [flags] <dump.txt>\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	results, err := codedump.Restore(fs.Arg(0), flDest, codedump.RestoreOptions{DryRun: flDryRun, OnMismatch: flOnMismatch, AllowParent: flAllowParent})
+	counts := map[codedump.RestoreStatus]int{}
+	mismatches := 0
+	for _, r := range results {
+		counts[r.Status]++
+		if r.Mismatch {
+			mismatches++
+			if r.Status != codedump.RestoreSkipped {
+				fmt.Fprintf(os.Stderr, "⚠️  warning: %s: content does not match #sha256, restored anyway\n", r.RelPath)
+			}
+		}
+		if r.Reason != "" {
+			fmt.Printf("%-10s %s (%s)\n", r.Status, r.RelPath, r.Reason)
+		} else {
+			fmt.Printf("%-10s %s\n", r.Status, r.RelPath)
+		}
+	}
+	if err != nil { fatal(err) }
+
+	verb := "Restored"
+	if flDryRun { verb = "Dry run:" }
+	fmt.Printf("%s %d new, %d changed, %d identical, %d skipped.\n", verb,
+		counts[codedump.RestoreNew], counts[codedump.RestoreChanged], counts[codedump.RestoreIdentical], counts[codedump.RestoreSkipped])
+	if mismatches > 0 {
+		fmt.Printf("Hash mismatches: %d.\n", mismatches)
+	}
+}
+
+// unpack implements -unpack: it writes every file of the dump under dir,
+// warning about files that did not match their #sha256.
+func unpack(dumpPath, dir string) {
+	n, err := codedump.Unpack(dumpPath, dir)
+	if errors.Is(err, codedump.ErrSHA256Mismatch) {
+		fmt.Fprintf(os.Stderr, "⚠️  warning: %v\n", err)
+	} else if err != nil {
+		fatal(err)
+	}
+	if dir == "" {
+		fmt.Printf("✅ Unpacked %d files.\n", n)
+		return
+	}
+	fmt.Printf("✅ Unpacked %d files into %s.\n", n, dir)
+}
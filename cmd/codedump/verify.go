@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/devMoisa/tool.codeDump/pkg/codedump"
+)
+
+// verify implements -verify: it lists the blocks of the dump whose content
+// does not match their recorded hash and exits non-zero if there are any.
+func verify(dumpPath string) {
+	mismatches, err := codedump.Verify(dumpPath)
+	for _, m := range mismatches {
+		fmt.Printf("mismatch   %s (#%s %s, content %s)\n", m.RelPath, m.Field, m.Want, m.Got)
+	}
+	if err != nil { fatal(err) }
+	if len(mismatches) > 0 {
+		fmt.Printf("%d blocks do not match their hashes.\n", len(mismatches))
+		os.Exit(1)
+	}
+	fmt.Printf("✅ All blocks of %s match their hashes.\n", dumpPath)
+}
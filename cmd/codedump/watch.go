@@ -0,0 +1,90 @@
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/devMoisa/tool.codeDump/pkg/codedump"
+)
+
+// watchPoll is how often -watch looks for changes.
+const watchPoll = 500 * time.Millisecond
+
+// watch implements -watch: it dumps once, then polls the target and dumps
+// again once changes have been quiet for debounce. A burst of edits (a branch
+// switch, a formatter run) thus causes a single regeneration, and none at all
+// if the collected files and their hashes end up as they were. The files a
+// dump writes are ignored, so a target holding them does not regenerate forever.
+func watch(c codedump.Config, debounce time.Duration, status io.Writer) {
+	wd, _ := os.Getwd()
+	targets := codedump.TargetDirs(wd, c)
+	own := codedump.OutputMatcher(wd, c)
+	// Polling must not repeat skip reports and notes every half second.
+	quiet := c
+	quiet.OnSkip, quiet.Notef = nil, nil
+
+	last := ""
+	regenerate := func() {
+		items, err := codedump.CollectTargets(targets, quiet)
+		if err != nil && !errors.Is(err, codedump.ErrUnreadable) {
+			fmt.Fprintf(os.Stderr, "❌ error: %v\n", err)
+			return
+		}
+		h := sha256.New()
+		for _, it := range items {
+			if own(it.Abs()) { continue }
+			fmt.Fprintf(h, "%s %s\n", it.Rel(), it.SHA256())
+		}
+		key := fmt.Sprintf("%x", h.Sum(nil))
+		if key == last {
+			fmt.Fprintf(status, "%s no changes to the dumped files, skipped.\n", time.Now().Format("15:04:05"))
+			return
+		}
+		outAbs, n, err := codedump.Dump(c)
+		if errors.Is(err, codedump.ErrUnreadable) {
+			fmt.Fprintf(os.Stderr, "⚠️  warning: %v\n", err)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ error: %v\n", err)
+			return
+		}
+		last = key
+		fmt.Fprintf(status, "%s ✅ Generated %q with %d files.\n", time.Now().Format("15:04:05"), outAbs, n)
+	}
+
+	regenerate()
+	fmt.Fprintf(status, "👀 Watching %s (debounce %s, Ctrl+C to stop)...\n", strings.Join(targets, ", "), debounce)
+	seen := watchSnapshot(targets, quiet, own)
+	for {
+		time.Sleep(watchPoll)
+		cur := watchSnapshot(targets, quiet, own)
+		if cur == seen { continue }
+		for {
+			time.Sleep(debounce)
+			next := watchSnapshot(targets, quiet, own)
+			if next == cur { break }
+			cur = next
+		}
+		seen = cur
+		regenerate()
+	}
+}
+
+// watchSnapshot fingerprints the files a dump would cover by path, size and
+// modification time, without reading them, leaving out those own matches.
+func watchSnapshot(targets []string, c codedump.Config, own func(string) bool) string {
+	items, err := codedump.CollectTargetPaths(targets, c)
+	if err != nil { return "error: " + err.Error() }
+	h := sha256.New()
+	for _, it := range items {
+		if own(it.Abs()) { continue }
+		var mod int64
+		if st, err := os.Stat(it.Abs()); err == nil { mod = st.ModTime().UnixNano() }
+		fmt.Fprintf(h, "%s %d %d\n", it.Rel(), it.Size(), mod)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
@@ -0,0 +1,88 @@
+package codedump
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseAge parses an age such as "90m", "36h", "30d" or "2w": a Go duration,
+// or a whole number of days (d) or weeks (w).
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v < 0 { return 0, fmt.Errorf("invalid age %q", s) }
+			return time.Duration(v) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 { return 0, fmt.Errorf("invalid age %q", s) }
+	return d, nil
+}
+
+// FormatAge renders d in its two largest units, for humans: "3d4h", "5h12m",
+// "40m", "12s". Negative ages, from clocks that disagree, count as "0s".
+func FormatAge(d time.Duration) string {
+	if d < 0 { d = 0 }
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}}
+	for i, u := range units {
+		if d < u.size && u.suffix != "s" { continue }
+		s := fmt.Sprintf("%d%s", d/u.size, u.suffix)
+		if i+1 < len(units) {
+			if rest := d % u.size / units[i+1].size; rest > 0 { s += fmt.Sprintf("%d%s", rest, units[i+1].suffix) }
+		}
+		return s
+	}
+	return "0s"
+}
+
+// ageFilter decides whether files fall within Config.OlderThan and
+// Config.NewerThan, judging age by modification time or, with Config.GitAge,
+// by the last commit that touched the file.
+type ageFilter struct {
+	now       time.Time
+	older     time.Duration // keep files at least this old (0 = no bound)
+	newer     time.Duration // keep files at most this old (0 = no bound)
+	committed map[string]time.Time
+}
+
+// newAgeFilter returns nil when no age bound is configured.
+func newAgeFilter(targetAbs string, c Config) (*ageFilter, error) {
+	if c.OlderThan == "" && c.NewerThan == "" { return nil, nil }
+	f := &ageFilter{now: time.Now()}
+	var err error
+	if c.OlderThan != "" {
+		if f.older, err = ParseAge(c.OlderThan); err != nil { return nil, fmt.Errorf("older_than: %w", err) }
+	}
+	if c.NewerThan != "" {
+		if f.newer, err = ParseAge(c.NewerThan); err != nil { return nil, fmt.Errorf("newer_than: %w", err) }
+	}
+	if c.GitAge {
+		if GitRoot(targetAbs) == "" {
+			c.warnf("git_age: %s is not in a git repository; using file modification times", targetAbs)
+		} else if f.committed, err = LastCommitTimes(targetAbs); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// keep reports whether the file at path, last modified at mtime, passes. With
+// git ages, files that were never committed count as changed just now.
+func (f *ageFilter) keep(path string, mtime time.Time) bool {
+	t := mtime
+	if f.committed != nil {
+		var ok bool
+		if t, ok = f.committed[resolved(path)]; !ok { t = f.now }
+	}
+	age := f.now.Sub(t)
+	if f.older > 0 && age < f.older { return false }
+	if f.newer > 0 && age > f.newer { return false }
+	return true
+}
@@ -0,0 +1,97 @@
+package codedump
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BlameLine is the commit that last touched one line of a file.
+type BlameLine struct {
+	Commit string // full hash; all zeros for uncommitted lines
+	Author string
+}
+
+// blameWidth is the width of the "<short sha> <initials> | " prefix
+// AnnotateBlame puts before every line.
+const blameWidth = len("0123456 ABC | ")
+
+// Blame runs git blame on the file at path and returns one entry per line.
+func Blame(path string) ([]BlameLine, error) {
+	out, err := gitOutput(filepath.Dir(path), "blame", "--line-porcelain", "--", filepath.Base(path))
+	if err != nil { return nil, err }
+	var lines []BlameLine
+	var cur BlameLine
+	for _, ln := range strings.Split(out, "\n") {
+		switch {
+		case strings.HasPrefix(ln, "\t"):
+			lines = append(lines, cur)
+		case strings.HasPrefix(ln, "author "):
+			cur.Author = strings.TrimPrefix(ln, "author ")
+		case len(ln) >= 40 && isHex(ln[:40]) && (len(ln) == 40 || ln[40] == ' '):
+			cur = BlameLine{Commit: ln[:40]}
+		}
+	}
+	return lines, nil
+}
+
+func isHex(s string) bool {
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) { return false }
+	}
+	return true
+}
+
+// AnnotateBlame prefixes every line of content with the short commit and
+// author initials of the matching line in original, as listed by blame.
+// Content may be a transformed copy of original: its lines are matched to
+// original in order, and lines with no unchanged counterpart (a stripped
+// comment, a "// ..." gap) get a blank prefix of the same width. Uncommitted
+// lines show dashes.
+func AnnotateBlame(content, original []byte, blame []BlameLine) []byte {
+	orig := bytes.Split(original, []byte("\n"))
+	lines := bytes.Split(content, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 { lines = lines[:len(lines)-1] }
+	var b bytes.Buffer
+	next := 0
+	for _, ln := range lines {
+		prefix := strings.Repeat(" ", blameWidth-2) + "| "
+		for j := next; j < len(orig) && j < len(blame); j++ {
+			if bytes.Equal(orig[j], ln) {
+				bl := blame[j]
+				sha, who := "-------", "---"
+				if strings.Trim(bl.Commit, "0") != "" { sha, who = bl.Commit[:7], initials(bl.Author) }
+				prefix = fmt.Sprintf("%s %-3s | ", sha, who)
+				next = j + 1
+				break
+			}
+		}
+		b.WriteString(prefix)
+		b.Write(ln)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+// initials returns up to three uppercase initials of name.
+func initials(name string) string {
+	var out []rune
+	for _, w := range strings.Fields(name) {
+		r, _ := utf8.DecodeRuneInString(w)
+		if unicode.IsLetter(r) { out = append(out, unicode.ToUpper(r)) }
+		if len(out) == 3 { break }
+	}
+	return string(out)
+}
+
+// StripBlame removes the prefixes AnnotateBlame added.
+func StripBlame(src []byte) []byte {
+	parts := bytes.Split(src, []byte("\n"))
+	for i, ln := range parts {
+		if len(ln) >= blameWidth && bytes.HasSuffix(ln[:blameWidth], []byte(" | ")) { parts[i] = ln[blameWidth:] }
+	}
+	return bytes.Join(parts, []byte("\n"))
+}
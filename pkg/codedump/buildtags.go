@@ -0,0 +1,33 @@
+package codedump
+
+import (
+	"bufio"
+	"bytes"
+	"go/build/constraint"
+	"strings"
+)
+
+// IsBuildIgnored reports whether Go source carries a build constraint that
+// excludes it via the "ignore" tag, such as "//go:build ignore" on standalone
+// generator scripts. Only the lines before the package clause are considered,
+// as the go tool does.
+func IsBuildIgnored(src []byte) bool {
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		ln := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(ln, "package ") { break }
+		if !constraint.IsGoBuild(ln) && !constraint.IsPlusBuild(ln) { continue }
+		expr, err := constraint.Parse(ln)
+		if err != nil { continue }
+		usesIgnore := false
+		expr.Eval(func(tag string) bool {
+			if tag == "ignore" { usesIgnore = true }
+			return false
+		})
+		// With every other tag satisfied, the file still drops out only
+		// because "ignore" is never set.
+		if usesIgnore && !expr.Eval(func(tag string) bool { return tag != "ignore" }) { return true }
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package codedump
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// checkpoint streams a dump straight to the output file and records, after each
+// block, how far the output got. An interrupted run can then be resumed: the
+// output is truncated to the last recorded offset and the files already written
+// are skipped. If one of those changed since, the header totals and its block
+// are stale, so the dump starts over instead.
+//
+// The checkpoint file holds a config hash line, then "H\t<offset>" once the
+// header is written and "F\t<offset>\t<est_tokens>\t<sha256>\t<rel_path>"
+// after every file block, whose token estimate lets a resumed run total the
+// header without reading the file again.
+type checkpoint struct {
+	path    string
+	f       *os.File // checkpoint log
+	out     *os.File // dump being written
+	done    map[string]bool
+	tokens  map[string]int    // est_tokens of the done files
+	sha     map[string]string // sha256 of the done files when written
+	resumed bool // the header is already in the output
+	pending int  // blocks written since the last sync
+}
+
+// checkpointSyncEvery is how many blocks are written between fsyncs.
+const checkpointSyncEvery = 100
+
+// openCheckpoint starts a checkpointed dump of items into outAbs. With resume,
+// an existing checkpoint for the same config is continued.
+func openCheckpoint(path, outAbs, configSHA string, resume bool, items []Item, c Config) (*checkpoint, error) {
+	cp := &checkpoint{path: path, done: map[string]bool{}, tokens: map[string]int{}, sha: map[string]string{}}
+	offset := int64(-1)
+	if resume {
+		var err error
+		offset, err = cp.load(configSHA)
+		if errors.Is(err, os.ErrNotExist) {
+			c.warnf("no checkpoint at %s, starting from scratch", path)
+		} else if err != nil {
+			return nil, err
+		}
+		if rel := cp.changed(items); offset >= 0 && rel != "" {
+			c.warnf("%s changed since checkpoint %s was written, starting from scratch", rel, path)
+			cp.done, cp.tokens, cp.sha = map[string]bool{}, map[string]int{}, map[string]string{}
+			offset = -1
+		}
+	}
+
+	if offset >= 0 {
+		out, err := os.OpenFile(outAbs, os.O_WRONLY, 0o644)
+		if err != nil { return nil, fmt.Errorf("resume: %w", err) }
+		if err := out.Truncate(offset); err != nil { out.Close(); return nil, err }
+		if _, err := out.Seek(offset, 0); err != nil { out.Close(); return nil, err }
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil { out.Close(); return nil, err }
+		cp.out, cp.f, cp.resumed = out, f, true
+		return cp, nil
+	}
+
+	if err := writeFile(OSFS{}, outAbs, nil); err != nil { return nil, err }
+	out, err := os.OpenFile(outAbs, os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil { return nil, err }
+	if err := writeFile(OSFS{}, path, []byte("config_sha256="+configSHA+"\n")); err != nil { out.Close(); return nil, err }
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil { out.Close(); return nil, err }
+	cp.out, cp.f = out, f
+	return cp, nil
+}
+
+// load reads an existing checkpoint, returning the output offset to resume at
+// (-1 if the header was never completed).
+func (cp *checkpoint) load(configSHA string) (int64, error) {
+	f, err := os.Open(cp.path)
+	if err != nil { return -1, err }
+	defer f.Close()
+	offset := int64(-1)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for first := true; sc.Scan(); first = false {
+		ln := sc.Text()
+		if first {
+			if ln != "config_sha256="+configSHA {
+				return -1, fmt.Errorf("checkpoint %s was written with a different config; rerun without -resume", cp.path)
+			}
+			continue
+		}
+		parts := strings.SplitN(ln, "\t", 5)
+		if len(parts) < 2 { continue }
+		n, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil { continue }
+		switch {
+		case parts[0] == "H":
+			offset = n
+		case parts[0] == "F" && len(parts) == 5:
+			tokens, err := strconv.Atoi(parts[2])
+			if err != nil { continue }
+			offset = n
+			cp.done[parts[4]] = true
+			cp.tokens[parts[4]] = tokens
+			cp.sha[parts[4]] = parts[3]
+		}
+	}
+	if err := sc.Err(); err != nil { return -1, err }
+	if offset < 0 { return -1, os.ErrNotExist }
+	return offset, nil
+}
+
+// changed returns the first done file that is no longer among items or whose
+// content differs from when it was written, "" if there is none.
+func (cp *checkpoint) changed(items []Item) string {
+	current := make(map[string]string, len(items))
+	for _, it := range items {
+		current[it.rel] = it.sha
+	}
+	var rels []string
+	for rel := range cp.done {
+		if sha, ok := current[rel]; !ok || sha != cp.sha[rel] { rels = append(rels, rel) }
+	}
+	if len(rels) == 0 { return "" }
+	sort.Strings(rels)
+	return rels[0]
+}
+
+// writeHeader writes the dump header unless resuming.
+func (cp *checkpoint) writeHeader(b []byte) error {
+	if cp.resumed { return nil }
+	return cp.record("H", b, "")
+}
+
+// writeBlock writes the block of it, estimated at tokens, and records it.
+func (cp *checkpoint) writeBlock(it Item, tokens int, b []byte) error {
+	return cp.record("F", b, fmt.Sprintf("\t%d\t%s\t%s", tokens, it.sha, it.rel))
+}
+
+func (cp *checkpoint) record(kind string, b []byte, suffix string) error {
+	if _, err := cp.out.Write(b); err != nil { return err }
+	off, err := cp.out.Seek(0, 1)
+	if err != nil { return err }
+	if cp.pending++; cp.pending >= checkpointSyncEvery {
+		cp.pending = 0
+		if err := cp.out.Sync(); err != nil { return err }
+	}
+	_, err = fmt.Fprintf(cp.f, "%s\t%d%s\n", kind, off, suffix)
+	return err
+}
+
+// finish closes the output and removes the checkpoint after a complete run.
+func (cp *checkpoint) finish() error {
+	err := cp.out.Close()
+	cp.f.Close()
+	if err != nil { return err }
+	return os.Remove(cp.path)
+}
+
+// abort closes the files but keeps the checkpoint for a later -resume.
+func (cp *checkpoint) abort() {
+	cp.out.Close()
+	cp.f.Close()
+}
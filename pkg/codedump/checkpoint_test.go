@@ -0,0 +1,113 @@
+package codedump
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// interruptFS fails every read once the checkpoint log records after files,
+// the way a killed run stops partway through the output.
+type interruptFS struct {
+	OSFS
+	log   string
+	after int
+}
+
+var errInterrupted = errors.New("interrupted")
+
+func (f interruptFS) ReadFile(name string) ([]byte, error) {
+	if b, err := os.ReadFile(f.log); err == nil && strings.Count(string(b), "\nF\t") >= f.after {
+		return nil, errInterrupted
+	}
+	return f.OSFS.ReadFile(name)
+}
+
+// checkpointFixture writes a few files and returns a checkpointed config
+// dumping them, with the path of its output.
+func checkpointFixture(t *testing.T) (Config, string) {
+	t.Helper()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"src/a.txt": "alpha\n",
+		"src/b.txt": "bravo\n",
+		"src/c.txt": "charlie\n",
+		"src/d.txt": "delta\n",
+		"src/e.txt": "echo\n",
+	})
+	c := DefaultConfig()
+	c.Root, c.Target, c.Out, c.Ext = dir, filepath.Join(dir, "src"), "dump.txt", ".txt"
+	c.Paths = PathsRelativeToOut
+	c.Checkpoint = "dump.ckpt"
+	return c, filepath.Join(dir, "dump.txt")
+}
+
+var generatedAt = regexp.MustCompile(`(?m)^// #generated_at: .*$`)
+
+// readOutput reads the dump at path with its generation time masked, so dumps
+// made in different seconds compare equal.
+func readOutput(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil { t.Fatal(err) }
+	if !generatedAt.Match(b) { t.Fatalf("%s has no generated_at line", path) }
+	return generatedAt.ReplaceAllString(string(b), "// #generated_at: -")
+}
+
+// interruptedDump runs c until the checkpoint records after files and checks
+// that it fails, leaving the checkpoint behind.
+func interruptedDump(t *testing.T, c Config, after int) {
+	t.Helper()
+	log := filepath.Join(c.Root, c.Checkpoint)
+	c.FS = interruptFS{log: log, after: after}
+	if _, _, err := Dump(c); !errors.Is(err, errInterrupted) { t.Fatalf("interrupted dump: err = %v", err) }
+	if _, err := os.Stat(log); err != nil { t.Fatalf("checkpoint not kept: %v", err) }
+}
+
+func TestCheckpointResume(t *testing.T) {
+	for after := 0; after <= 4; after++ {
+		c, out := checkpointFixture(t)
+		if _, _, err := Dump(c); err != nil { t.Fatal(err) }
+		want := readOutput(t, out)
+		if err := os.Remove(out); err != nil { t.Fatal(err) }
+
+		interruptedDump(t, c, after)
+		c.Resume = true
+		if _, _, err := Dump(c); err != nil { t.Fatalf("after %d: resume: %v", after, err) }
+		if got := readOutput(t, out); got != want {
+			t.Errorf("after %d: resumed dump differs:\n%s\nwant:\n%s", after, got, want)
+		}
+		if _, err := os.Stat(filepath.Join(c.Root, c.Checkpoint)); !os.IsNotExist(err) {
+			t.Errorf("after %d: checkpoint not removed: %v", after, err)
+		}
+	}
+}
+
+func TestCheckpointResumeChangedFile(t *testing.T) {
+	c, out := checkpointFixture(t)
+	interruptedDump(t, c, 3)
+	for _, name := range []string{"src/b.txt", "src/e.txt"} {
+		if err := os.WriteFile(filepath.Join(c.Root, name), []byte("changed "+name+"\n"), 0o644); err != nil { t.Fatal(err) }
+	}
+	var warnings []string
+	c.Warnf = func(format string, args ...any) { warnings = append(warnings, format) }
+	c.Resume = true
+	if _, _, err := Dump(c); err != nil { t.Fatal(err) }
+	got := readOutput(t, out)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "changed since checkpoint") {
+		t.Errorf("warnings = %q, want one about the changed file", warnings)
+	}
+
+	c.Resume, c.Warnf = false, nil
+	if _, _, err := Dump(c); err != nil { t.Fatal(err) }
+	if want := readOutput(t, out); got != want {
+		t.Errorf("resumed dump differs:\n%s\nwant:\n%s", got, want)
+	}
+	for _, s := range []string{"changed src/b.txt", "changed src/e.txt"} {
+		if !strings.Contains(got, s) { t.Errorf("resumed dump lacks %q", s) }
+	}
+	if strings.Contains(got, "bravo") { t.Errorf("resumed dump kept the old content of src/b.txt") }
+}
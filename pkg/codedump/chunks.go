@@ -0,0 +1,29 @@
+package codedump
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// ChunkHashes returns the hex sha256 of each run of n lines of data, newlines
+// included, in order. The last chunk may be shorter. A change to a few lines
+// changes only the hashes of the chunks holding them, as long as the line
+// count stays the same.
+func ChunkHashes(data []byte, n int) []string {
+	var out []string
+	for len(data) > 0 {
+		end := 0
+		for i := 0; i < n && end < len(data); i++ {
+			if j := bytes.IndexByte(data[end:], '\n'); j >= 0 {
+				end += j + 1
+			} else {
+				end = len(data)
+			}
+		}
+		sum := sha256.Sum256(data[:end])
+		out = append(out, hex.EncodeToString(sum[:]))
+		data = data[end:]
+	}
+	return out
+}
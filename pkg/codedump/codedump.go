@@ -1,34 +1,162 @@
 package codedump
 
 import (
+	"bufio"
 	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"go/build"
+	"go/token"
 	"io"
+	"math/rand"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // DefaultRCName is the default name for the RC/config file.
 const DefaultRCName = ".codedumprc"
 
+// DefaultIgnoreFile is the default Config.IgnoreFile.
+const DefaultIgnoreFile = ".codedumpignore"
+
 // Config holds the parameters for a dump run.
 type Config struct {
 	Root    string // where the final TXT will be saved
-	Target  string // folder to scan
+	Target  string // folder to scan, or several separated by commas
 	Out     string // output file name (relative to Root)
-	Ext     string // file extension to include
-	Include string // optional substring filter (path/content)
-	Exclude string // comma-separated substrings to skip (path)
+	Ext     string // comma-separated file extensions to include ("" = all files)
+	Include string // optional comma-separated substrings, one of which the path must contain
+	Exclude string // comma-separated substrings to skip, of the path relative to Target; a leading "/" anchors to Target, "**/" matches at any depth
 	Pkg     bool   // keep "package" line if true
+	Format  string // output format name (see Formats); "" = DefaultFormat
+
+	StripExts string // comma-separated extensions package stripping applies to
+
+	BranchDiff bool   // only files changed since the merge base with BaseBranch
+	BaseBranch string // branch to diff against ("" = default branch)
+
+	SimilarityDedupe float64 // collapse files at least this similar (0..1) to an earlier one; 0 = off
+	Dedup            bool    // collapse files with the #sha256 of an earlier one into a #duplicate_of reference
+	SkipVendored     bool    // skip vendor/ dirs and the Go module cache regardless of Exclude
+	Summary          string  // optional JSON summary sidecar path (relative to Root)
+	TrimCommentsTo   int     // shorten leading comment blocks to this many lines; 0 = keep
+	Paths            string  // base for #rel_path: "cwd" (default) or "relative-to-out"
+	Graph            string  // optional Graphviz DOT path for Go package dependencies (relative to Root)
+	Expect           string  // per-extension count checks, e.g. "go>=1,proto>=1"
+	PackageDocs      bool    // emit each Go package's doc comment before its files
+	Tree             bool    // open the dump with an ASCII tree of the dumped files, like the tree command
+	PackagesOverview bool    // open the dump with a table of the Go packages: import path, files, lines
+	ModGraph         bool    // open the dump with the direct and indirect requirements of Target's go.mod
+	CheckGoVersion   bool    // record whether the go directive of Target's go.mod matches the running Go as #go_version_match
+	StrictGoVersion  bool    // like CheckGoVersion, but fail the dump when they do not match
+	HashTree         string  // optional Merkle hash tree JSON path (relative to Root)
+	FollowEmbeds     bool    // also include files referenced by //go:embed in collected Go files
+	Funcs            string  // comma-separated func name globs; Go files keep only those funcs
+	Uses             string  // only files importing this package or referencing this symbol
+	Checkpoint       string  // stream the dump and log progress here so it can be resumed (relative to Root)
+	Resume           bool    `json:"-"` // continue an interrupted run from Checkpoint
+	Shuffle          bool    // emit files in a pseudo-random order derived from Seed
+	Seed             int64   // seed for Shuffle; the same seed gives the same order
+	Sort             string  // file order: SortPath, SortSize, SortMtime or SortSHA ("" = SortPath)
+	SortDesc         bool    // reverse Sort; files with equal keys stay in path order
+	SizeGroup        bool    // with Sort SortSize, emit small, medium and large files in sections, by path within each
+	SizeGroupSmall   int64   // SizeGroup files below this many bytes are small (0 = DefaultSizeGroupSmall)
+	SizeGroupLarge   int64   // SizeGroup files of at least this many bytes are large (0 = DefaultSizeGroupLarge)
+	DupeReport       string  // optional duplicated-block report JSON path (relative to Root)
+	DupeWindow       int     // lines per block for DupeReport (0 = DefaultDupeWindow)
+	TodoIndex        string  // optional TODO/FIXME index path (relative to Root)
+	TodoKeywords     string  // comma-separated markers for TodoIndex ("" = DefaultTodoKeywords)
+	CollapseBlanks   bool    // collapse runs of blank lines to a single one
+	SkipBuildIgnore  bool    // skip Go files constrained out with //go:build ignore
+	FileSummary      bool    // add a #summary: line with each file's first doc comment sentence
+	PruneGlob        string  // comma-separated base-name globs for directories never descended into
+	WithTestdata     bool    // include testdata/ directories below Target, which are left out by default
+	MaxDepth         int     // only dump files at most this many levels below Target, 1 = its own files (0 = no limit)
+	ObjectStore      string  // optional content-addressed store dir (relative to Root)
+	WarnLineLength   int     // flag files with a line longer than this many characters (0 = off)
+	SkipLongLines    bool    // with WarnLineLength, leave such files out instead of flagging them
+	Strip            string  // comma-separated content transforms to strip with: "comments"
+	StripComments    bool    // remove comments from .go files, after package stripping (see also Strip)
+	RelativizePaths  bool    // rewrite the absolute Root path inside file content to RootPlaceholder
+	GroupBy          string  // "owner" to order files by CODEOWNERS owner, one section each ("" = off)
+	OnePerDir        bool    // keep only the first file of each directory in Sort order
+	CoverProfile     string  // go test -coverprofile output used by AnnotateCoverage
+	AnnotateCoverage bool    // prefix uncovered statement lines of .go files with CoverageMarker
+	ChatDelimiter    string  // file delimiters of the chat format: "xml" (default) or "markdown"
+	ChatPreamble     string  // system message of the chat format ("" = a generated description)
+	SkipSymlinks     bool    // leave out symlinked files (default true)
+	FollowSymlinks   bool    // walk into symlinked directories as if they were part of the tree, skipping links back to an ancestor
+	OlderThan        string  // keep files at least this old, e.g. "30d" ("" = no bound)
+	NewerThan        string  // keep files at most this old, e.g. "2w" ("" = no bound)
+	GitAge           bool    // judge OlderThan/NewerThan by last commit date instead of mtime
+	RelativeAge      bool    // record each file's #mod_time and its #age at generation time, e.g. 3d4h
+	Grep             string  // keep only files whose content matches this regexp ("" = all)
+	GrepContext      int     // with Grep, emit only matching lines plus this many around them (-1 = whole file)
+	GitIgnore        bool    // skip paths ignored by .gitignore files from the repository root down
+	IgnoreFile       string  // gitignore-syntax file at the Target root whose patterns join Exclude ("" = none)
+	Concurrency      int     `json:"-"` // files read and hashed at once by Collect (0 = runtime.NumCPU())
+	FailFast         bool    `json:"-"` // stop at the first file that cannot be read instead of leaving it out and reporting ErrUnreadable
+	ModelsSummary    bool    // reduce .go files to their struct type definitions
+	DocsOnly         bool    // reduce .go files to their doc comments and declaration signatures
+	DocsSkipOther    bool    // with DocsOnly, leave out files that are not .go instead of keeping them whole
+	TimestampOut     bool    // insert the generation time into the Out file name
+	TimestampLayout  string  // Go time layout for TimestampOut ("" = DefaultTimestampLayout)
+	Replace          string  // comma-separated old=new literal substitutions applied to emitted content
+	PackageReadmes   bool    // emit the README.md of each directory with dumped files before its files
+	MaxTokens        int     // stop adding files once their estimated tokens would exceed this (0 = no limit)
+	ChunkHashes      int     // record the sha256 of every run of this many lines as #chunk_sha256 (0 = off)
+	CompressionStats bool    // record each file's gzip-compressed size as #compressed_bytes
+	MaxDirFiles      int     // skip a directory whole when it directly holds more than this many files with Ext (0 = no limit)
+	MaxFileSize      int64   // skip files larger than this many bytes (0 = no limit)
+	SkipEmpty        bool    // leave out zero-byte files
+	Files            string  // comma-separated files to dump, relative to Target, in this order; replaces the walk and its path filters
+	BinaryPreview    int     // replace the content of binary files with a hex dump of their first this many bytes (0 = dump them as is)
+	Filters          string  // comma-separated names of filters added with RegisterFilter, applied in order
+	RequireUTF8      bool    // reject files whose content is not valid UTF-8, as OnInvalid says
+	OnInvalid        string  // with RequireUTF8: InvalidError to abort or InvalidSkip to leave the file out ("" = InvalidError)
+	BlameFiles       string  // comma-separated globs on #rel_path; matching files get git blame line prefixes
+	Parts            int     // split the output into this many files (0 or 1 = a single file)
+	MaxOutputBytes   int64   // split the output into numbered parts of at most this many bytes (0 = one file)
+	BalanceParts     bool    // with Parts, pack files largest-first to even out part sizes instead of keeping order
+
+	CommentStyles map[string]CommentStyle `json:",omitempty"` // per-extension overrides of the built-in comment table
+	StripModes    map[string]string       `json:",omitempty"` // per-extension header strip modes (StripGoPackage, ...) overriding StripExts
+
+	OnSkip func(path, reason string)          `json:"-"` // called for every file or dir Collect leaves out
+	Warnf  func(format string, args ...any) `json:"-"` // receives non-fatal warnings (nil = discard)
+	Notef  func(format string, args ...any) `json:"-"` // receives verbose-only notices (nil = discard)
+	FS     Filesystem                       `json:"-"` // file access for walking, reading and writing (nil = OSFS)
+
+	RCPath string `json:"-"` // RC file the config was loaded from ("" if none)
 }
 
+// SHA256Original is the #sha256_of header value: each block's #sha256 is the
+// hash of the original file, before any transform. Blocks whose content was
+// transformed also carry #sha256_dumped, the hash of the content as written.
+const SHA256Original = "original"
+
+// Values for Config.Paths.
+const (
+	PathsCWD           = "cwd"
+	PathsRelativeToOut = "relative-to-out"
+	PathsRelativeToGit = "relative-to-git"
+)
+
+// Values for Config.OnInvalid.
+const (
+	InvalidError = "error"
+	InvalidSkip  = "skip"
+)
+
 // DefaultConfig returns sane defaults for the tool.
 func DefaultConfig() Config {
 	return Config{
@@ -36,113 +164,1333 @@ func DefaultConfig() Config {
 		Target:  "./models",
 		Out:     "models_tree.txt",
 		Ext:     ".go",
-		Exclude: "_test.go,/.git/,/vendor/",
+		Exclude: "_test.go,**/.git/,**/vendor/",
 		Pkg:     false,
+
+		StripExts:    ".go",
+		SkipSymlinks: true,
+		GrepContext:  -1,
+		IgnoreFile:   DefaultIgnoreFile,
 	}
 }
 
 // Item represents one collected file.
 type Item struct {
-	rel  string
-	abs  string
-	sha  string
-	size int64
+	rel   string
+	abs   string
+	sha   string
+	size  int64
+	mode  os.FileMode
+	mtime time.Time
+
+	embeddedBy string // rel path of the Go file whose //go:embed pulled this file in
+	longLines  bool   // has a line longer than Config.WarnLineLength
+	dirOmitted int    // files of the same directory dropped by Config.OnePerDir
+
+	fs Filesystem // where the file was collected from
 }
 
-// Dump generates the concatenated output and writes it to the configured Out path.
-// It returns the absolute output path and the number of files written.
+// Rel returns the file path as recorded in #rel_path.
+func (it Item) Rel() string { return it.rel }
+
+// Abs returns the absolute path of the file.
+func (it Item) Abs() string { return it.abs }
+
+// SHA256 returns the hex sha256 of the file content.
+func (it Item) SHA256() string { return it.sha }
+
+// Size returns the file size in bytes.
+func (it Item) Size() int64 { return it.size }
+
+// PackageReadmeName is the file Config.PackageReadmes looks for in each directory.
+const PackageReadmeName = "README.md"
+
+// StdoutOut is the Out value that makes Dump write to standard output.
+const StdoutOut = "-"
+
+// Dump generates the concatenated output and writes it to the configured Out path
+// (standard output for StdoutOut). It returns the absolute output path and the
+// number of files written.
+// Configs that split the output into several parts must use DumpParts.
+//
+// Files that cannot be read are left out, and once the dump of the others is
+// written the error wraps ErrUnreadable and lists them; set Config.FailFast to
+// stop at the first one instead.
 func Dump(c Config) (string, int, error) {
+	if c.SplitsOutput() {
+		return "", 0, fmt.Errorf("parts and max_output_bytes write several files; use DumpParts")
+	}
+	if c.Out == StdoutOut {
+		n, err := DumpTo(c, os.Stdout)
+		return StdoutOut, n, err
+	}
+	parts, n, err := DumpParts(c)
+	if len(parts) == 0 { return "", 0, err }
+	return parts[0].Path, n, err
+}
+
+// DumpParts is like Dump but returns every output file written. With
+// Config.Parts > 1 the files are spread over that many parts, named after Out
+// with a ".partN" suffix before the extension. With Config.MaxOutputBytes they
+// fill as many ".001", ".002", ... parts as needed; otherwise there is one part.
+func DumpParts(c Config) ([]Part, int, error) {
+	return dump(c, nil)
+}
+
+// DumpTo is like Dump but writes the output to w instead of the Out path, and
+// returns the number of files written. Sidecar files (summary, hash_tree, ...)
+// are still written where configured. Parts and checkpoints need real files
+// and are rejected.
+func DumpTo(c Config, w io.Writer) (int, error) {
+	if c.SplitsOutput() {
+		return 0, fmt.Errorf("parts and max_output_bytes write several files; use DumpParts")
+	}
+	if c.Checkpoint != "" {
+		return 0, fmt.Errorf("checkpoint needs an output file; use Dump")
+	}
+	_, n, err := dump(c, w)
+	return n, err
+}
+
+// dump implements DumpParts and DumpTo: each part is written to w, or to its
+// file when w is nil.
+func dump(c Config, w io.Writer) ([]Part, int, error) {
 	wd, _ := os.Getwd()
 	rootAbs := AbsFrom(wd, c.Root)
-	targetAbs := AbsFrom(wd, c.Target)
+	targets := TargetDirs(wd, c)
+	targetAbs := targets[0] // for the checks that look at one Go module
 	outAbs := AbsFrom(rootAbs, c.Out)
+	genTime := time.Now()
+	if c.TimestampOut {
+		outAbs = timestampedPath(outAbs, genTime, c.TimestampLayout)
+	}
+	if c.Out == StdoutOut {
+		if w == nil { return nil, 0, fmt.Errorf("out=%s needs Dump or DumpTo", StdoutOut) }
+		outAbs = StdoutOut
+	}
 
-	items, err := Collect(targetAbs, c)
-	if err != nil { return "", 0, err }
-
-	var buf bytes.Buffer
-	now := time.Now().Format(time.RFC3339)
-	fmt.Fprintf(&buf, "// ===== CODEDUMP GENERATED =====\n")
-	fmt.Fprintf(&buf, "// #pwd: %s\n", wd)
-	fmt.Fprintf(&buf, "// #generated_at: %s\n", now)
-	fmt.Fprintf(&buf, "// #go_version: %s\n", runtime.Version())
-	fmt.Fprintf(&buf, "// #goroot: %s\n", build.Default.GOROOT)
-	fmt.Fprintf(&buf, "// #root: %s\n", filepath.ToSlash(rootAbs))
-	fmt.Fprintf(&buf, "// #target: %s\n", filepath.ToSlash(targetAbs))
-	fmt.Fprintf(&buf, "// #out: %s\n", filepath.ToSlash(outAbs))
-	fmt.Fprintf(&buf, "// =================================\n\n")
+	if c.Resume && c.Checkpoint == "" {
+		return nil, 0, fmt.Errorf("resume needs a checkpoint file")
+	}
+	if c.TimestampOut && c.Checkpoint != "" {
+		return nil, 0, fmt.Errorf("checkpoint cannot be combined with timestamp_out")
+	}
 
-	for _, it := range items {
-		data, err := os.ReadFile(it.abs)
-		if err != nil { return "", 0, err }
-		content := data
-		if !c.Pkg {
-			content = StripPackageLine(data)
+	if _, err := newFormatter(c); err != nil { return nil, 0, err }
+	if c.Checkpoint != "" && c.Format != "" && c.Format != DefaultFormat {
+		return nil, 0, fmt.Errorf("checkpoint only supports the %s format", DefaultFormat)
+	}
+	for _, s := range SplitClean(c.Strip) {
+		if s != "comments" { return nil, 0, fmt.Errorf("strip: unknown transform %q", s) }
+	}
+	for ext, m := range c.StripModes {
+		if err := checkStripMode(m); err != nil { return nil, 0, fmt.Errorf("strip_mode%s: %w", ext, err) }
+	}
+	if c.AnnotateCoverage && c.CoverProfile == "" {
+		return nil, 0, fmt.Errorf("annotate-coverage needs a coverprofile")
+	}
+	if c.AnnotateCoverage && c.Funcs != "" {
+		return nil, 0, fmt.Errorf("annotate-coverage cannot be combined with funcs")
+	}
+	if c.ModelsSummary && c.Funcs != "" {
+		return nil, 0, fmt.Errorf("models-summary cannot be combined with funcs")
+	}
+	if c.DocsOnly && (c.Funcs != "" || c.ModelsSummary) {
+		return nil, 0, fmt.Errorf("docs-only cannot be combined with funcs or models-summary")
+	}
+	grepRe, err := compileGrep(c)
+	if err != nil { return nil, 0, err }
+	replacements, err := ParseReplacements(c.Replace)
+	if err != nil { return nil, 0, err }
+	blameGlobs := SplitClean(c.BlameFiles)
+	for _, g := range blameGlobs {
+		if _, err := path.Match(g, ""); err != nil { return nil, 0, fmt.Errorf("blame_files %q: %w", g, err) }
+	}
+	if c.SplitsOutput() && c.Checkpoint != "" {
+		return nil, 0, fmt.Errorf("checkpoint cannot be combined with parts or max_output_bytes")
+	}
+	if c.Parts > 1 && c.MaxOutputBytes > 0 {
+		return nil, 0, fmt.Errorf("parts cannot be combined with max_output_bytes")
+	}
+	if c.GroupBy != "" && c.GroupBy != "owner" {
+		return nil, 0, fmt.Errorf("group-by: unknown grouping %q (want owner)", c.GroupBy)
+	}
+	if c.SizeGroup && c.Sort != SortSize {
+		return nil, 0, fmt.Errorf("size_group requires sort=size")
+	}
+	if c.SizeGroup && c.GroupBy != "" {
+		return nil, 0, fmt.Errorf("size_group cannot be combined with group_by")
+	}
+
+	var sum *Summary
+	if c.Summary != "" {
+		sum = newSummary(&c)
+	}
+
+	items, bigDirs, failed, err := collectTargets(targets, c, true)
+	if err != nil { return nil, 0, err }
+	if c.Expect != "" {
+		if err := CheckExpect(c.Expect, items); err != nil { return nil, 0, err }
+	}
+	if c.Shuffle {
+		rng := rand.New(rand.NewSource(c.Seed))
+		rng.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+	}
+	var groups, groupTitles map[string]string
+	groupSize := map[string]int{}
+	if c.GroupBy == "owner" {
+		if groups, err = ownerGroups(rootAbs, items); err != nil { return nil, 0, err }
+		for _, g := range groups { groupSize[g]++ }
+		sort.SliceStable(items, func(i, j int) bool {
+			gi, gj := groups[items[i].abs], groups[items[j].abs]
+			if (gi == Unowned) != (gj == Unowned) { return gj == Unowned }
+			return gi < gj
+		})
+	}
+	if c.SizeGroup {
+		if groups, groupTitles, err = sizeGroups(items, c.SizeGroupSmall, c.SizeGroupLarge, c.SortDesc); err != nil { return nil, 0, err }
+		for _, g := range groups { groupSize[g]++ }
+	}
+
+	var similar *similarityIndex
+	if c.SimilarityDedupe > 0 {
+		similar = &similarityIndex{threshold: c.SimilarityDedupe}
+	}
+	var firstBySHA map[string]string // sha -> rel of its first file, for Dedup
+	collapsed := map[string]bool{}    // abs paths of the Dedup duplicates
+	if c.Dedup {
+		firstBySHA = map[string]string{}
+	}
+
+	var dumped map[string]bool // abs paths of the dumped files, for PackageReadmes
+	if c.PackageReadmes {
+		dumped = map[string]bool{}
+		for _, it := range items {
+			dumped[it.abs] = true
+		}
+	}
+	var mods *moduleResolver
+	if c.PackageDocs || c.AnnotateCoverage || c.PackagesOverview || c.ModGraph || c.CheckGoVersion || c.StrictGoVersion {
+		mods = newModuleResolver()
+	}
+	var goCheck []Field // #go_directive and #go_version_match, for CheckGoVersion
+	if c.CheckGoVersion || c.StrictGoVersion {
+		if mod := mods.module(targetAbs); mod != nil {
+			data, err := os.ReadFile(filepath.Join(mod.Dir, "go.mod"))
+			if err != nil { return nil, 0, err }
+			directive := goDirective(data)
+			match := GoVersionMatches(directive, runtime.Version())
+			if !match && c.StrictGoVersion {
+				return nil, 0, fmt.Errorf("strict_go_version: go.mod has go %q, but this is %s", directive, runtime.Version())
+			}
+			goCheck = []Field{{"go_directive", directive}, {"go_version_match", fmt.Sprint(match)}}
+		} else {
+			c.warnf("check_go_version: %s is not inside a Go module", targetAbs)
+		}
+	}
+	modDeps := "" // MODULE DEPS section body, for ModGraph
+	if c.ModGraph {
+		if mod := mods.module(targetAbs); mod != nil {
+			data, err := os.ReadFile(filepath.Join(mod.Dir, "go.mod"))
+			if err != nil { return nil, 0, err }
+			modDeps = moduleDepsSection(mod, data)
+		} else {
+			c.warnf("mod_graph: %s is not inside a Go module, leaving out MODULE DEPS", targetAbs)
+		}
+	}
+	// A checkpointed dump has a single part (see above), so its checkpoint is
+	// opened before anything is read: files an interrupted run already wrote
+	// are then skipped from the start.
+	var cp *checkpoint
+	if c.Checkpoint != "" {
+		if cp, err = openCheckpoint(AbsFrom(rootAbs, c.Checkpoint), outAbs, ConfigSHA256(c), c.Resume, items, c); err != nil { return nil, 0, err }
+		defer func() {
+			if cp != nil { cp.abort() }
+		}()
+	}
+	var goPkgs map[string]PackageInfo // per-file entries, for PackagesOverview
+	if c.PackagesOverview && (cp == nil || !cp.resumed) {
+		goPkgs = map[string]PackageInfo{}
+		fset := token.NewFileSet()
+		for _, it := range items {
+			p, ok, err := goPackageOf(it, mods, fset)
+			if err != nil { return nil, 0, err }
+			if ok { goPkgs[it.abs] = p }
+		}
+	}
+	var cover CoverProfile
+	if c.AnnotateCoverage {
+		if cover, err = ReadCoverProfile(AbsFrom(wd, c.CoverProfile)); err != nil { return nil, 0, err }
+	}
+	// Blocks are built one at a time as they are written. The header comes
+	// first, though, with the token total, and MaxTokens drops files up
+	// front, so a pre-pass works out the content of every block beforehand.
+	// It also decides which blocks dedup and similarity_dedupe collapse, as
+	// that depends on the files before them.
+	tokens, lines := map[string]int{}, map[string]int{}
+	refs := map[string][]Field{} // #duplicate_of or #similar_to fields, by abs path
+	// dedupRef returns the #duplicate_of field of it for Dedup, if an earlier
+	// file has the same hash.
+	dedupRef := func(it Item) []Field {
+		if firstBySHA == nil { return nil }
+		if first, ok := firstBySHA[it.sha]; ok {
+			collapsed[it.abs] = true
+			return []Field{{"duplicate_of", first}}
+		}
+		firstBySHA[it.sha] = it.rel
+		return nil
+	}
+	// buildFile builds the block of it. In the pre-pass (pre) it decides the
+	// block's references, and leaves out the metadata that does not change
+	// the content unless blocks are measured for MaxOutputBytes.
+	buildFile := func(it Item, pre bool) (File, error) {
+		light := pre && c.MaxOutputBytes == 0
+		data, err := it.read()
+		if err != nil { return File{}, err }
+		src, uncovered := data, 0
+		if cover != nil && strings.HasSuffix(it.abs, ".go") {
+			key := mods.importPath(filepath.Dir(it.abs)) + "/" + filepath.Base(it.abs)
+			src, uncovered = AnnotateUncovered(data, cover.UncoveredLines(key))
+		}
+		file := File{
+			Rel:     it.rel,
+			Size:    it.size,
+			SHA256:  it.sha,
+			Mode:    it.mode,
+			Content: transformContent(c, it, src),
+			Raw:     data,
+		}
+		if !light && c.ChunkHashes > 0 && len(data) > 0 {
+			file.Meta = append(file.Meta, Field{"chunk_sha256", strings.Join(ChunkHashes(data, c.ChunkHashes), ",")})
+		}
+		if !light && c.CompressionStats {
+			file.Meta = append(file.Meta, Field{"compressed_bytes", fmt.Sprint(GzipSize(data))})
+		}
+		if c.BinaryPreview > 0 && IsBinary(data) {
+			file.Meta = append(file.Meta, Field{"binary", "true"}, Field{"hex_preview", fmt.Sprint(min(len(data), c.BinaryPreview))})
+		}
+		if c.RelativeAge {
+			file.Meta = append(file.Meta, Field{"mod_time", it.mtime.Format(time.RFC3339)}, Field{"age", FormatAge(genTime.Sub(it.mtime))})
+		}
+		if uncovered > 0 {
+			file.Meta = append(file.Meta, Field{"uncovered_lines", fmt.Sprint(uncovered)})
+		}
+		if grepRe != nil && c.GrepContext >= 0 {
+			var ok bool
+			if file.Content, ok = Excerpt(file.Content, grepRe, c.GrepContext, excerptGap(c, it.rel)); ok {
+				file.Meta = append(file.Meta, Field{"excerpt", "true"})
+			}
+		}
+		if pathsMode(c) != PathsRelativeToOut {
+			file.Abs = filepath.ToSlash(it.abs)
+		}
+		if c.RelativizePaths {
+			var n int
+			if file.Content, n = RelativizePaths(file.Content, rootAbs); n > 0 {
+				file.Meta = append(file.Meta, Field{"root_replacements", fmt.Sprint(n)})
+			}
+		}
+		if len(replacements) > 0 {
+			var n int
+			if file.Content, n = ApplyReplacements(file.Content, replacements); n > 0 {
+				file.Meta = append(file.Meta, Field{"replacements", fmt.Sprint(n)})
+			}
+		}
+		plain := file.Content
+		if uncovered > 0 { plain = StripCoverageMarks(plain) }
+		transformed := !bytes.Equal(plain, data)
+		if transformed {
+			file.Meta = append(file.Meta, Field{"transformed", "true"})
 		}
-		fmt.Fprintf(&buf, "// ===== BEGIN FILE =====\n")
-		fmt.Fprintf(&buf, "// #rel_path: %s\n", it.rel)
-		fmt.Fprintf(&buf, "// #abs_path: %s\n", filepath.ToSlash(it.abs))
-		fmt.Fprintf(&buf, "// #size_bytes: %d\n", it.size)
-		fmt.Fprintf(&buf, "// #sha256: %s\n", it.sha)
-		fmt.Fprintf(&buf, "// ======================\n")
-		io.Copy(&buf, bytes.NewReader(content))
-		if len(content) > 0 && content[len(content)-1] != '\n' {
-			buf.WriteByte('\n')
+		if !light && c.FileSummary {
+			if sum := FileSummary(it.rel, data, fileSummaryMax); sum != "" {
+				file.Meta = append(file.Meta, Field{"summary", sum})
+			}
+		}
+		if it.dirOmitted > 0 {
+			file.Meta = append(file.Meta, Field{"dir_omitted", fmt.Sprint(it.dirOmitted)})
+		}
+		if it.longLines {
+			file.Meta = append(file.Meta, Field{"has_long_lines", "true"})
+		}
+		if it.embeddedBy != "" {
+			file.Meta = append(file.Meta, Field{"embedded_by", it.embeddedBy})
+		}
+		if pre {
+			ref := dedupRef(it)
+			if similar != nil && ref == nil {
+				if rep, sim, ok := similar.match(it.rel, file.Content); ok {
+					ref = []Field{{"similar_to", rep}, {"similarity", fmt.Sprintf("%.2f", sim)}}
+				}
+			}
+			refs[it.abs] = ref
+		}
+		file.Meta = append(file.Meta, refs[it.abs]...)
+		file.NoContent = len(refs[it.abs]) > 0
+		if matchAny(it.rel, blameGlobs) && !file.NoContent {
+			bl, err := Blame(it.abs)
+			if err != nil { return File{}, fmt.Errorf("blame %s: %w", it.rel, err) }
+			file.Content = AnnotateBlame(file.Content, src, bl)
+			file.Meta = append(file.Meta, Field{"blame", "true"})
+		}
+		if transformed && !file.NoContent {
+			// #sha256 stays the original file's, which restore and compare
+			// rely on; this one lets Verify check the block as dumped.
+			file.Meta = append(file.Meta, Field{"sha256_dumped", sha256Hex(file.Content)})
+		}
+		if !file.NoContent {
+			tokens[it.abs] = EstimateTokens(file.Content)
+			lines[it.abs] = CountLines(file.Content)
+			file.Meta = append(file.Meta, Field{"est_tokens", fmt.Sprint(tokens[it.abs])}, Field{"lines", fmt.Sprint(lines[it.abs])})
+		}
+		return file, nil
+	}
+	blockSize := map[string]int64{} // bytes each block adds to a part, for MaxOutputBytes
+	total := 0
+	for i, it := range items {
+		if cp != nil && cp.done[it.rel] && similar == nil {
+			// Already in the output. Only the dedup state needs carrying on,
+			// which takes no reading; similarity would need the content.
+			refs[it.abs] = dedupRef(it)
+			total += cp.tokens[it.rel]
+			continue
+		}
+		file, err := buildFile(it, true)
+		if err != nil { return nil, 0, err }
+		if total += tokens[it.abs]; c.MaxTokens > 0 && total > c.MaxTokens {
+			for _, rest := range items[i:] {
+				if c.OnSkip != nil { c.OnSkip(rest.abs, "max-tokens") }
+			}
+			c.warnf("max_tokens: left out the last %d of %d files to stay within %d estimated tokens", len(items)-i, len(items), c.MaxTokens)
+			items = items[:i]
+			break
+		}
+		if c.MaxOutputBytes > 0 {
+			if blockSize[it.abs], err = blockBytes(c, file); err != nil { return nil, 0, err }
+		}
+	}
+
+	docs, readmeTexts := map[string]string{}, map[string]string{} // by directory, read once
+	// renderPart renders part k of n, holding list, to w, building one block
+	// at a time. With a checkpoint, the header and every finished block are
+	// handed to it instead, and only the footer goes to w. With measure, no
+	// block is built: the caller adds up blockSize for them.
+	renderPart := func(w io.Writer, outPath string, k, n int, list []Item, cp *checkpoint, measure bool) error {
+		f, err := newFormatter(c)
+		if err != nil { return err }
+		introduced, readmes := map[string]bool{}, map[string]bool{}
+		partTokens, partLines, partDupes := 0, 0, 0
+		for _, it := range list {
+			partTokens += tokens[it.abs]
+			partLines += lines[it.abs]
+			if collapsed[it.abs] { partDupes++ }
+		}
+		h := Header{Fields: []Field{
+			{"pwd", wd},
+			{"generated_at", genTime.Format(time.RFC3339)},
+			{"go_version", runtime.Version()},
+		}}
+		h.Fields = append(h.Fields, goCheck...)
+		h.Fields = append(h.Fields, []Field{
+			{"goroot", build.Default.GOROOT},
+			{"root", filepath.ToSlash(rootAbs)},
+			targetField(targets),
+			{"out", filepath.ToSlash(outPath)},
+			{"rc", rcLabel(wd, c.RCPath)},
+			{"config_sha256", ConfigSHA256(c)},
+			{"paths", pathsMode(c)},
+			{"sha256_of", SHA256Original},
+			{"est_tokens", fmt.Sprint(partTokens)},
+			{"lines", fmt.Sprint(partLines)},
+		}...)
+		if c.Dedup {
+			h.Fields = append(h.Fields, Field{"duplicates", fmt.Sprint(partDupes)})
+		}
+		if n > 1 {
+			h.Fields = append(h.Fields, Field{"part", fmt.Sprintf("%d/%d", k, n)})
+		}
+		var buf bytes.Buffer // what is written before it goes to w or cp
+		if err := f.WriteHeader(&buf, h); err != nil { return err }
+		sections, _ := f.(SectionWriter)
+		if c.Tree && sections != nil {
+			if err := sections.WriteSection(&buf, "TREE", strings.TrimSuffix(RenderTree(list), "\n")); err != nil { return err }
+		}
+		if modDeps != "" && sections != nil {
+			if err := sections.WriteSection(&buf, "MODULE DEPS", modDeps); err != nil { return err }
+		}
+		if goPkgs != nil && sections != nil {
+			var pkgFiles []PackageInfo
+			for _, it := range list {
+				if p, ok := goPkgs[it.abs]; ok { pkgFiles = append(pkgFiles, p) }
+			}
+			if err := sections.WriteSection(&buf, "PACKAGES", packagesTable(mergePackages(pkgFiles))); err != nil { return err }
+		}
+		if sections != nil {
+			for _, d := range bigDirs {
+				if err := sections.WriteSection(&buf, fmt.Sprintf("SKIPPED DIR: too many files (%d)", d.files), d.rel); err != nil { return err }
+			}
+		}
+		if cp != nil {
+			if err := cp.writeHeader(buf.Bytes()); err != nil { return err }
+		} else if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+		buf.Reset()
+
+		group := ""
+		for _, it := range list {
+			resumed := cp != nil && cp.done[it.rel]
+			if g := groups[it.abs]; g != group {
+				group = g
+				if sections != nil && !resumed {
+					title := "OWNER: " + g
+					if groupTitles != nil { title = groupTitles[g] }
+					if err := sections.WriteSection(&buf, title, fmt.Sprintf("files: %d", groupSize[g])); err != nil { return err }
+				}
+			}
+			if resumed {
+				introduced[filepath.Dir(it.abs)] = true
+				readmes[filepath.Dir(it.abs)] = true
+				continue
+			}
+			if dir := filepath.Dir(it.abs); c.PackageDocs && sections != nil && strings.HasSuffix(it.abs, ".go") && !introduced[dir] {
+				introduced[dir] = true
+				docText, ok := docs[dir]
+				if !ok {
+					if docText, err = PackageDoc(dir); err != nil { return err }
+					docs[dir] = docText
+				}
+				if docText != "" {
+					if err := sections.WriteSection(&buf, "PACKAGE: "+mods.importPath(dir), docText); err != nil { return err }
+				}
+			}
+			if dir := filepath.Dir(it.abs); c.PackageReadmes && sections != nil && !readmes[dir] {
+				readmes[dir] = true
+				text, ok := readmeTexts[dir]
+				if !ok {
+					readme := filepath.Join(dir, PackageReadmeName)
+					if b, err := c.fsys().ReadFile(readme); err == nil && !dumped[readme] { text = strings.TrimRight(string(b), "\n") }
+					readmeTexts[dir] = text
+				}
+				if text != "" {
+					if err := sections.WriteSection(&buf, "README: "+path.Dir(it.rel), text); err != nil { return err }
+				}
+			}
+			if !measure {
+				file, err := buildFile(it, false)
+				if err != nil { return err }
+				if err := f.WriteFile(&buf, file); err != nil { return err }
+			}
+			if err := flushBlock(cp, w, &buf, it, tokens[it.abs]); err != nil { return err }
+		}
+		if err := f.WriteFooter(&buf); err != nil { return err }
+		_, err = w.Write(buf.Bytes())
+		return err
+	}
+
+	partItems := [][]Item{items}
+	var partName func(k int) string
+	switch {
+	case c.Parts > 1:
+		partItems = splitParts(items, c.Parts, c.BalanceParts)
+		partName = func(k int) string { return partPath(outAbs, k) }
+	case c.MaxOutputBytes > 0:
+		partName = func(k int) string { return chunkPath(outAbs, k) }
+		// Measure with the largest part number and count a part could get,
+		// so the real headers are never longer than the measured ones.
+		measure := func(list []Item) (int64, error) {
+			var size countWriter
+			err := renderPart(&size, partName(len(items)), len(items), len(items), list, nil, true)
+			for _, it := range list {
+				size += countWriter(blockSize[it.abs])
+			}
+			return int64(size), err
+		}
+		if partItems, err = splitBySize(items, c.MaxOutputBytes, measure); err != nil { return nil, 0, err }
+	}
+	parts := make([]Part, len(partItems))
+	for pi := range parts {
+		parts[pi].Path = outAbs
+		if len(parts) > 1 { parts[pi].Path = partName(pi + 1) }
+	}
+
+	for pi, list := range partItems {
+		part := &parts[pi]
+		var dst io.Writer
+		var pw *partWriter
+		switch {
+		case cp != nil:
+			dst = cp.out
+		case w != nil:
+			dst = w
+		default:
+			if pw, err = newPartWriter(c.fsys(), part.Path); err != nil { return nil, 0, err }
+			dst = pw
+		}
+		var size countWriter
+		if err := renderPart(io.MultiWriter(dst, &size), part.Path, pi+1, len(parts), list, cp, false); err != nil {
+			if pw != nil { pw.abort() }
+			return nil, 0, err
+		}
+		part.Files, part.Bytes = len(list), int64(size)
+		if pw != nil {
+			if err := pw.commit(); err != nil { return nil, 0, err }
+		}
+		if cp != nil {
+			err := cp.finish()
+			cp = nil
+			if err != nil { return nil, 0, err }
+			if fi, err := os.Stat(part.Path); err == nil { part.Bytes = fi.Size() }
+		}
+		if c.MaxOutputBytes > 0 && part.Bytes > c.MaxOutputBytes {
+			c.warnf("%s is %d bytes, over max_output_bytes (%d): %s alone does not fit", part.Path, part.Bytes, c.MaxOutputBytes, list[0].rel)
+		}
+	}
+	if len(parts) > 1 && c.MaxOutputBytes > 0 {
+		if err := writePartIndex(c.fsys(), chunkIndexPath(outAbs), parts); err != nil { return nil, 0, err }
+	}
+	if sum != nil {
+		if err := sum.write(c.fsys(), AbsFrom(rootAbs, c.Summary), genTime, items); err != nil { return nil, 0, err }
+	}
+	if c.HashTree != "" {
+		if err := writeHashTree(c.fsys(), AbsFrom(rootAbs, c.HashTree), items); err != nil { return nil, 0, err }
+	}
+	if c.DupeReport != "" {
+		if err := writeDupeReport(c.fsys(), AbsFrom(rootAbs, c.DupeReport), items, c.DupeWindow); err != nil { return nil, 0, err }
+	}
+	if c.TodoIndex != "" {
+		if err := writeTodoIndex(c.fsys(), AbsFrom(rootAbs, c.TodoIndex), items, c.TodoKeywords); err != nil { return nil, 0, err }
+	}
+	if c.ObjectStore != "" {
+		if err := writeObjectStore(c.fsys(), AbsFrom(rootAbs, c.ObjectStore), items); err != nil { return nil, 0, err }
+	}
+	if c.Graph != "" {
+		dot, err := PackageGraph(items)
+		if err != nil { return nil, 0, err }
+		if err := writeFile(c.fsys(), AbsFrom(rootAbs, c.Graph), dot); err != nil { return nil, 0, err }
+	}
+	return parts, len(items), unreadableError(failed)
+}
+
+// DefaultTimestampLayout is the time layout used by Config.TimestampOut.
+const DefaultTimestampLayout = "20060102T150405"
+
+// timestampedPath inserts "-<t>" before the extension of out, formatted with
+// layout ("" = DefaultTimestampLayout): "dump.txt" becomes "dump-20240115T103000.txt".
+func timestampedPath(out string, t time.Time, layout string) string {
+	if layout == "" { layout = DefaultTimestampLayout }
+	ext := filepath.Ext(out)
+	return strings.TrimSuffix(out, ext) + "-" + t.Format(layout) + ext
+}
+
+// isOutputName reports whether a file named name is a dump this config writes,
+// including earlier timestamped ones, so dumps never ingest each other.
+func isOutputName(c Config, name string) bool {
+	if name == c.Out { return true }
+	if !c.TimestampOut { return false }
+	out := filepath.Base(c.Out)
+	ext := filepath.Ext(out)
+	ok, _ := filepath.Match(strings.TrimSuffix(out, ext)+"-*"+ext, name)
+	return ok
+}
+
+// OutputMatcher returns a func reporting whether the file at an absolute path
+// is one a dump with c, run from wd, writes: the dump with its parts, their
+// index and earlier timestamped names, the checkpoint, and the sidecars. A
+// watcher ignores these, or every dump would look like a change.
+func OutputMatcher(wd string, c Config) func(path string) bool {
+	rootAbs := AbsFrom(wd, c.Root)
+	outAbs := AbsFrom(rootAbs, c.Out)
+	dir, ext := filepath.Dir(outAbs), filepath.Ext(outAbs)
+	stem := strings.TrimSuffix(filepath.Base(outAbs), ext)
+	names := []string{stem + ext, stem + ".part*" + ext, stem + ".[0-9][0-9][0-9]*" + ext, stem + ".index.json"}
+	if c.TimestampOut { names = append(names, stem+"-*"+ext) }
+	files := map[string]bool{}
+	for _, p := range []string{c.Checkpoint, c.Summary, c.HashTree, c.DupeReport, c.TodoIndex, c.Graph} {
+		if p != "" { files[AbsFrom(rootAbs, p)] = true }
+	}
+	objects := "" // ObjectStore is a directory
+	if c.ObjectStore != "" { objects = AbsFrom(rootAbs, c.ObjectStore) + string(filepath.Separator) }
+
+	return func(path string) bool {
+		if files[path] || (objects != "" && strings.HasPrefix(path, objects)) { return true }
+		if c.Out == StdoutOut || filepath.Dir(path) != dir { return false }
+		for _, name := range names {
+			if ok, _ := filepath.Match(name, filepath.Base(path)); ok { return true }
+		}
+		return false
+	}
+}
+
+// newFormatter returns a fresh formatter for c.Format, configured from c.
+func newFormatter(c Config) (Formatter, error) {
+	f, err := NewFormatter(c.Format)
+	if err != nil { return nil, err }
+	if cf, ok := f.(ConfigurableFormatter); ok {
+		if err := cf.Configure(c); err != nil { return nil, err }
+	}
+	return f, nil
+}
+
+// SplitsOutput reports whether c spreads the dump over several files, which
+// only DumpParts supports.
+func (c Config) SplitsOutput() bool { return c.Parts > 1 || c.MaxOutputBytes > 0 }
+
+// transformContent applies the configured content transforms to a file's data.
+func transformContent(c Config, it Item, data []byte) []byte {
+	if c.BinaryPreview > 0 && IsBinary(data) {
+		return []byte(hex.Dump(data[:min(len(data), c.BinaryPreview)]))
+	}
+	content := data
+	if c.Funcs != "" && strings.HasSuffix(it.abs, ".go") {
+		content = FilterFuncs(content, SplitClean(c.Funcs))
+	}
+	if c.ModelsSummary && strings.HasSuffix(it.abs, ".go") {
+		content = FilterStructs(content)
+	}
+	if c.DocsOnly && strings.HasSuffix(it.abs, ".go") {
+		content = ExtractDocs(content)
+	}
+	cs, known := c.commentStyle(it.rel)
+	if c.TrimCommentsTo > 0 && known {
+		content = trimLeadingComments(cs, content, c.TrimCommentsTo)
+	}
+	if known && hasTransform(c.Strip, "comments") {
+		content = StripComments(cs, content)
+	}
+	if !c.Pkg {
+		content = stripHeader(c.stripMode(it.rel), cs, known, content)
+	}
+	if c.StripComments && strings.HasSuffix(it.abs, ".go") {
+		content = StripGoComments(content)
+	}
+	if c.CollapseBlanks {
+		content = CollapseBlankRuns(content)
+	}
+	return content
+}
+
+// hasTransform reports whether name is one of the comma-separated transforms in list.
+func hasTransform(list, name string) bool {
+	for _, s := range SplitClean(list) {
+		if s == name { return true }
+	}
+	return false
+}
+
+// flushBlock hands a finished block, with its token estimate, to the
+// checkpoint if there is one and writes it to w otherwise, emptying buf.
+func flushBlock(cp *checkpoint, w io.Writer, buf *bytes.Buffer, it Item, tokens int) error {
+	defer buf.Reset()
+	if cp != nil { return cp.writeBlock(it, tokens, buf.Bytes()) }
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// blockBytes returns how many bytes file adds to a dump in c's format, at
+// most: the larger of what a first and a second copy of it add, so separators
+// between blocks and footer entries such as a zip's directory count too.
+func blockBytes(c Config, file File) (int64, error) {
+	var sizes [3]countWriter
+	for i := range sizes {
+		f, err := newFormatter(c)
+		if err != nil { return 0, err }
+		if err := f.WriteHeader(&sizes[i], Header{}); err != nil { return 0, err }
+		for k := 0; k < i; k++ {
+			if err := f.WriteFile(&sizes[i], file); err != nil { return 0, err }
 		}
-		fmt.Fprintln(&buf, "// ===== END FILE =====\n")
+		if err := f.WriteFooter(&sizes[i]); err != nil { return 0, err }
 	}
+	return int64(max(sizes[1]-sizes[0], sizes[2]-sizes[1])), nil
+}
+
+// partWriter streams one output file. With OSFS it writes to a temporary file
+// next to path, which commit renames into place, so a failed run leaves any
+// earlier dump untouched. Other Filesystems only offer WriteFile, so there
+// the part is collected in memory and written by commit.
+type partWriter struct {
+	io.Writer
+	fsys Filesystem
+	path string
+	tmp  *os.File      // OSFS only
+	bw   *bufio.Writer // OSFS only
+	buf  *bytes.Buffer // other Filesystems
+}
+
+func newPartWriter(fsys Filesystem, path string) (*partWriter, error) {
+	if _, ok := fsys.(OSFS); !ok {
+		buf := &bytes.Buffer{}
+		return &partWriter{Writer: buf, fsys: fsys, path: path, buf: buf}, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { return nil, err }
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil { return nil, err }
+	bw := bufio.NewWriter(tmp)
+	return &partWriter{Writer: bw, fsys: fsys, path: path, tmp: tmp, bw: bw}, nil
+}
+
+// commit puts the written part in place.
+func (p *partWriter) commit() error {
+	if p.tmp == nil { return writeFile(p.fsys, p.path, p.buf.Bytes()) }
+	err := p.bw.Flush()
+	if cerr := p.tmp.Close(); err == nil { err = cerr }
+	if err == nil { err = os.Chmod(p.tmp.Name(), 0o644) }
+	if err == nil { err = os.Rename(p.tmp.Name(), p.path) }
+	if err != nil { os.Remove(p.tmp.Name()) }
+	return err
+}
+
+// abort drops the written part.
+func (p *partWriter) abort() {
+	if p.tmp == nil { return }
+	p.tmp.Close()
+	os.Remove(p.tmp.Name())
+}
+
+// writeFile writes data to path, creating parent directories as needed.
+func writeFile(fsys Filesystem, path string, data []byte) error {
+	if err := fsys.MkdirAll(filepath.Dir(path), 0o755); err != nil { return err }
+	return fsys.WriteFile(path, data, 0o644)
+}
+
+// ConfigSHA256 returns a stable hash of the effective config, so two dumps can be
+// checked for having been produced with the same settings.
+func ConfigSHA256(c Config) string {
+	b, _ := json.Marshal(c)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
 
-	if err := os.MkdirAll(filepath.Dir(outAbs), 0o755); err != nil { return "", 0, err }
-	if err := os.WriteFile(outAbs, buf.Bytes(), 0o644); err != nil { return "", 0, err }
-	return outAbs, len(items), nil
+// rcLabel renders the RC path for the header, relative to wd when possible.
+func rcLabel(wd, rc string) string {
+	if rc == "" { return "(none)" }
+	if rel, err := filepath.Rel(wd, AbsFrom(wd, rc)); err == nil { return filepath.ToSlash(rel) }
+	return filepath.ToSlash(rc)
+}
+
+// pathsMode returns the normalized Paths setting.
+func pathsMode(c Config) string {
+	if c.Paths == "" { return PathsCWD }
+	return c.Paths
+}
+
+// relBase returns the directory #rel_path values are computed against.
+func relBase(wd string, c Config) (string, error) {
+	switch pathsMode(c) {
+	case PathsCWD:
+		return wd, nil
+	case PathsRelativeToOut:
+		return filepath.Dir(AbsFrom(AbsFrom(wd, c.Root), c.Out)), nil
+	case PathsRelativeToGit:
+		if top := GitRoot(TargetDirs(wd, c)[0]); top != "" { return top, nil }
+		c.warnf("%s: target is not inside a git repository, using paths relative to %s", PathsRelativeToGit, wd)
+		return wd, nil
+	}
+	return "", fmt.Errorf("unknown paths mode %q (want %s, %s or %s)", c.Paths, PathsCWD, PathsRelativeToOut, PathsRelativeToGit)
+}
+
+// fsys returns c.FS, or OSFS when it is unset.
+func (c Config) fsys() Filesystem {
+	if c.FS == nil { return OSFS{} }
+	return c.FS
+}
+
+// warnf forwards a warning to c.Warnf when set.
+func (c Config) warnf(format string, args ...any) {
+	if c.Warnf != nil { c.Warnf(format, args...) }
+}
+
+// workers is the number of files Collect reads at once.
+func (c Config) workers() int {
+	if c.Concurrency > 0 { return c.Concurrency }
+	return runtime.NumCPU()
+}
+
+// notef forwards a notice to c.Notef when set.
+func (c Config) notef(format string, args ...any) {
+	if c.Notef != nil { c.Notef(format, args...) }
 }
 
 // Collect walks the target directory, applying filters, and returns metadata for each file.
 func Collect(targetAbs string, c Config) ([]Item, error) {
+	items, _, failed, err := collect(targetAbs, c, true)
+	if err != nil { return nil, err }
+	return items, unreadableError(failed)
+}
+
+// CollectPaths is Collect without hashing: items carry no SHA256, and files
+// are only read when a content filter (uses, grep, ...) needs them. It is the
+// cheap way to see what a dump would cover.
+func CollectPaths(targetAbs string, c Config) ([]Item, error) {
+	items, _, failed, err := collect(targetAbs, c, false)
+	if err != nil { return nil, err }
+	return items, unreadableError(failed)
+}
+
+// ErrUnreadable is reported, joined with the error of every file, when some
+// files could not be read. Without Config.FailFast, Collect still returns the
+// readable files alongside it and Dump still writes them, so callers can treat
+// it as a warning, checking for it with errors.Is.
+var ErrUnreadable = errors.New("some files could not be read")
+
+// unreadableError joins failed under ErrUnreadable, or returns nil if empty.
+func unreadableError(failed []error) error {
+	if len(failed) == 0 { return nil }
+	return errors.Join(append([]error{ErrUnreadable}, failed...)...)
+}
+
+// CollectTargets is Collect over several target directories, such as the
+// ones TargetDirs returns. Their files are merged into one list, sorted by
+// rel path unless Config.Sort says otherwise, and a file under two of them
+// is listed once. Config.Files needs a single target.
+func CollectTargets(targets []string, c Config) ([]Item, error) {
+	items, _, failed, err := collectTargets(targets, c, true)
+	if err != nil { return nil, err }
+	return items, unreadableError(failed)
+}
+
+// CollectTargetPaths is CollectTargets without hashing, like CollectPaths.
+func CollectTargetPaths(targets []string, c Config) ([]Item, error) {
+	items, _, failed, err := collectTargets(targets, c, false)
+	if err != nil { return nil, err }
+	return items, unreadableError(failed)
+}
+
+// TargetDirs returns the absolute directories c.Target lists, resolved
+// against wd, in order and without repeats. An empty Target is wd itself.
+func TargetDirs(wd string, c Config) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, t := range SplitClean(c.Target) {
+		abs := AbsFrom(wd, t)
+		if seen[abs] { continue }
+		seen[abs] = true
+		out = append(out, abs)
+	}
+	if len(out) == 0 { out = []string{AbsFrom(wd, "")} }
+	return out
+}
+
+// targetField is the header field naming the targets: #target for a single
+// one, as always, or #targets listing them all.
+func targetField(targets []string) Field {
+	if len(targets) == 1 { return Field{"target", filepath.ToSlash(targets[0])} }
+	list := make([]string, len(targets))
+	for i, t := range targets {
+		list[i] = filepath.ToSlash(t)
+	}
+	return Field{"targets", strings.Join(list, ", ")}
+}
+
+// collectTargets runs collect on every target and merges the results.
+func collectTargets(targets []string, c Config, hash bool) ([]Item, []bigDir, []error, error) {
+	if len(targets) == 1 { return collect(targets[0], c, hash) }
+	if c.Files != "" { return nil, nil, nil, fmt.Errorf("files cannot be combined with several targets") }
+	var out []Item
+	var bigDirs []bigDir
+	var failed []error
+	seen := map[string]bool{} // abs paths, for targets inside one another
+	for _, t := range targets {
+		items, big, fails, err := collect(t, c, hash)
+		if err != nil { return nil, nil, nil, err }
+		for _, it := range items {
+			if seen[it.abs] { continue }
+			seen[it.abs] = true
+			out = append(out, it)
+		}
+		bigDirs = append(bigDirs, big...)
+		failed = append(failed, fails...)
+	}
+	sortItems(out, c.Sort, c.SortDesc)
+	return out, bigDirs, failed, nil
+}
+
+// bigDir is a directory left out by Config.MaxDirFiles.
+type bigDir struct {
+	rel   string
+	files int // matching files directly inside it
+}
+
+// collect implements Collect and CollectPaths. It also returns the directories
+// skipped for holding too many files and, unless c.FailFast is set, the errors
+// of the files and directories it could not read, both in walk order.
+func collect(targetAbs string, c Config, hash bool) ([]Item, []bigDir, []error, error) {
 	excl := SplitClean(c.Exclude)
+	exts, incl := SplitClean(c.Ext), SplitClean(c.Include)
+	prune := SplitClean(c.PruneGlob)
+	for _, g := range prune {
+		if _, err := filepath.Match(g, ""); err != nil { return nil, nil, nil, fmt.Errorf("prune_glob %q: %w", g, err) }
+	}
 	wd, _ := os.Getwd()
+	base, err := relBase(wd, c)
+	if err != nil { return nil, nil, nil, err }
+	var paths []string
 	var out []Item
+	var bigDirs []bigDir
+	var failed []error
 
-	err := filepath.WalkDir(targetAbs, func(path string, d os.DirEntry, err error) error {
-		if err != nil { return err }
+	var changed map[string]bool
+	if c.BranchDiff {
+		var err error
+		if changed, err = BranchDiffFiles(targetAbs, c.BaseBranch); err != nil { return nil, nil, nil, err }
+	}
+	ages, err := newAgeFilter(targetAbs, c)
+	if err != nil { return nil, nil, nil, err }
+	grepRe, err := compileGrep(c)
+	if err != nil { return nil, nil, nil, err }
+	var ignore *gitignore
+	if c.GitIgnore {
+		if ignore, err = newGitignore(c.fsys(), targetAbs); err != nil { return nil, nil, nil, err }
+	}
+	var rootIgnore *gitignore
+	if c.IgnoreFile != "" {
+		if rootIgnore, err = newRootIgnore(c.fsys(), targetAbs, c.IgnoreFile); err != nil { return nil, nil, nil, err }
+	}
+	if err := checkSort(c.Sort); err != nil { return nil, nil, nil, err }
+	switch c.OnInvalid {
+	case "", InvalidError, InvalidSkip:
+	default: return nil, nil, nil, fmt.Errorf("on-invalid: unknown mode %q (want error or skip)", c.OnInvalid)
+	}
+	named, err := lookupFilters(c.Filters)
+	if err != nil { return nil, nil, nil, err }
+	needData := c.Uses != "" || c.SkipBuildIgnore || grepRe != nil || c.WarnLineLength > 0 || c.RequireUTF8 || len(named) > 0
+	var vendored vendoredChecker
+	if c.SkipVendored {
+		vendored = newVendoredChecker(targetAbs)
+	}
+	skip := func(path, reason string) {
+		if c.OnSkip != nil { c.OnSkip(path, reason) }
+	}
+	// unreadable records err for path and reports nil so the walk goes on,
+	// or returns err as is with FailFast.
+	unreadable := func(path string, err error) error {
+		if c.FailFast { return err }
+		failed = append(failed, err)
+		skip(path, "unreadable")
+		return nil
+	}
+
+	var visit func(path string, d os.DirEntry, err error) error
+	// followDir walks the directory a symlink at path points to as if it were
+	// at path, unless it is one of path's own ancestors.
+	followDir := func(path string) error {
+		st, err := c.fsys().Stat(path)
+		if err != nil { return unreadable(path, err) }
+		for p := filepath.Dir(path); ; p = filepath.Dir(p) {
+			if a, err := c.fsys().Stat(p); err == nil && os.SameFile(a, st) {
+				skip(path, "symlink-cycle")
+				return nil
+			}
+			if p == filepath.Dir(p) { break }
+		}
+		real, err := filepath.EvalSymlinks(path)
+		if err != nil { return unreadable(path, err) }
+		return c.fsys().WalkDir(real, func(p string, d os.DirEntry, err error) error {
+			rel, _ := filepath.Rel(real, p)
+			return visit(filepath.Join(path, rel), d, err)
+		})
+	}
+	visit = func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if path == targetAbs { return err }
+			if err := unreadable(path, err); err != nil { return err }
+			if d != nil && d.IsDir() { return filepath.SkipDir }
+			return nil
+		}
 		if d.IsDir() {
-			pp := filepath.ToSlash(path)
-			for _, bad := range excl {
-				if bad != "" && strings.Contains(pp, bad) {
+			if path != targetAbs && matchAny(d.Name(), prune) { skip(path, "prune"); return filepath.SkipDir }
+			if path != targetAbs && !c.WithTestdata && d.Name() == "testdata" { skip(path, "testdata"); return filepath.SkipDir }
+			if c.MaxDepth > 0 && path != targetAbs && dirDepth(targetAbs, path) >= c.MaxDepth {
+				skip(path, "max-depth")
+				return filepath.SkipDir
+			}
+			if c.SkipVendored && path != targetAbs && vendored.isVendored(path) {
+				skip(path, "vendored")
+				return filepath.SkipDir
+			}
+			if path != targetAbs && excluded(targetAbs, path, true, excl) {
+				skip(path, "exclude")
+				return filepath.SkipDir
+			}
+			if rootIgnore != nil && path != targetAbs && rootIgnore.ignored(path, true) {
+				skip(path, "exclude")
+				return filepath.SkipDir
+			}
+			if ignore != nil && path != targetAbs {
+				if ignore.ignored(path, true) { skip(path, "gitignore"); return filepath.SkipDir }
+				if err := ignore.load(c.fsys(), path); err != nil { return err }
+			}
+			if c.MaxDirFiles > 0 && path != targetAbs {
+				n, err := countDirFiles(c.fsys(), path, exts)
+				if err != nil {
+					if err := unreadable(path, err); err != nil { return err }
+					return filepath.SkipDir
+				}
+				if n > c.MaxDirFiles {
+					rel, _ := filepath.Rel(base, path)
+					bigDirs = append(bigDirs, bigDir{filepath.ToSlash(rel), n})
+					skip(path, "too-many-files")
 					return filepath.SkipDir
 				}
 			}
 			return nil
 		}
-		if !strings.HasSuffix(path, c.Ext) { return nil }
-		if filepath.Base(path) == c.Out { return nil }
+		if c.FollowSymlinks && d.Type()&os.ModeSymlink != 0 {
+			if st, err := c.fsys().Stat(path); err == nil && st.IsDir() { return followDir(path) }
+		}
+		if c.SkipSymlinks && d.Type()&os.ModeSymlink != 0 { skip(path, "symlink"); return nil }
+		if !endsWithAny(path, exts) { skip(path, "ext"); return nil }
+		if isOutputName(c, filepath.Base(path)) { skip(path, "output"); return nil }
+		if c.DocsOnly && c.DocsSkipOther && !strings.HasSuffix(path, ".go") { skip(path, "docs-only"); return nil }
 
 		pp := filepath.ToSlash(path)
-		if c.Include != "" && !strings.Contains(pp, c.Include) { return nil }
-		for _, bad := range excl {
-			if bad != "" && strings.Contains(pp, bad) { return nil }
+		if len(incl) > 0 && !containsAny(pp, incl) { skip(path, "include"); return nil }
+		if excluded(targetAbs, path, false, excl) { skip(path, "exclude"); return nil }
+		if rootIgnore != nil && rootIgnore.ignored(path, false) { skip(path, "exclude"); return nil }
+		if ignore != nil && ignore.ignored(path, false) { skip(path, "gitignore"); return nil }
+		if changed != nil && !changed[resolved(path)] { skip(path, "branch-diff"); return nil }
+		if c.SkipVendored && vendored.isVendored(path) { skip(path, "vendored"); return nil }
+		if c.SkipEmpty || c.MaxFileSize > 0 {
+			info, err := d.Info()
+			if err != nil { return unreadable(path, err) }
+			if c.SkipEmpty && info.Size() == 0 { skip(path, "empty"); return nil }
+			if c.MaxFileSize > 0 && info.Size() > c.MaxFileSize {
+				c.warnf("skipping %s: %d bytes is over max_file_size (%d)", path, info.Size(), c.MaxFileSize)
+				skip(path, "too-large")
+				return nil
+			}
+		}
+		if ages != nil {
+			info, err := d.Info()
+			if err != nil { return unreadable(path, err) }
+			if !ages.keep(path, info.ModTime()) { skip(path, "age"); return nil }
 		}
 
-		st, err := os.Stat(path)
-		if err != nil { return err }
-		data, err := os.ReadFile(path)
-		if err != nil { return err }
-		sum := sha256.Sum256(data)
-		rel, _ := filepath.Rel(wd, path)
-		out = append(out, Item{
-			rel:  filepath.ToSlash(rel),
-			abs:  path,
-			sha:  hex.EncodeToString(sum[:]),
-			size: st.Size(),
-		})
+		paths = append(paths, path)
+		return nil
+	}
+	if c.Files != "" {
+		paths, err = explicitPaths(c.fsys(), targetAbs, c.Files)
+	} else {
+		err = c.fsys().WalkDir(targetAbs, visit)
+	}
+	if err != nil { return nil, nil, nil, err }
+
+	// Reading and hashing is the slow part, so it runs on a worker pool. Skip
+	// reasons and notes are reported afterwards, in walk order, so OnSkip and
+	// Notef never run concurrently.
+	results := make([]collected, len(paths))
+	err = parallel(len(paths), c.workers(), func(i int) error {
+		path, r := paths[i], &results[i]
+		var data []byte
+		var err error
+		if hash {
+			r.item, data, err = readItem(c.fsys(), path, base)
+		} else if r.item, err = statItem(c.fsys(), path, base); err == nil && needData {
+			data, err = c.fsys().ReadFile(path)
+		}
+		if err != nil {
+			if c.FailFast { return err }
+			r.err = err
+			return nil
+		}
+		if c.RequireUTF8 && !utf8.Valid(data) {
+			if c.OnInvalid != InvalidSkip { return fmt.Errorf("%s: content is not valid UTF-8", path) }
+			r.skip = "invalid-utf8"
+			return nil
+		}
+		switch {
+		case c.Uses != "" && !FileUses(path, data, c.Uses): r.skip = "uses"
+		case c.SkipBuildIgnore && strings.HasSuffix(path, ".go") && IsBuildIgnored(data): r.skip = "build-ignore"
+		case grepRe != nil && !grepRe.Match(data): r.skip = "grep"
+		case c.WarnLineLength > 0:
+			if r.longest = LongestLine(data); r.longest > c.WarnLineLength && c.SkipLongLines { r.skip = "long-lines" }
+		}
+		for _, f := range named {
+			if r.skip != "" { break }
+			keep, err := f.fn(r.item, data)
+			if err != nil { return fmt.Errorf("filter %s: %s: %w", f.name, path, err) }
+			if !keep { r.skip = "filter:" + f.name }
+		}
 		return nil
 	})
-	if err != nil { return nil, err }
+	if err != nil { return nil, nil, nil, err }
+	for i, r := range results {
+		if r.err != nil { failed = append(failed, r.err); skip(paths[i], "unreadable"); continue }
+		if r.skip != "" { skip(paths[i], r.skip); continue }
+		if c.WarnLineLength > 0 && r.longest > c.WarnLineLength {
+			c.notef("%s has a %d-character line (limit %d)", r.item.rel, r.longest, c.WarnLineLength)
+			r.item.longLines = true
+		}
+		out = append(out, r.item)
+	}
+
+	if c.FollowEmbeds {
+		if out, err = followEmbeds(c.fsys(), out, base); err != nil { return nil, nil, nil, err }
+	}
 
-	sort.Slice(out, func(i, j int) bool { return out[i].rel < out[j].rel })
+	if c.Files == "" || c.Sort != "" {
+		sortItems(out, c.Sort, c.SortDesc)
+	}
+	if c.OnePerDir {
+		out = firstPerDir(out, skip)
+	}
+	return out, bigDirs, failed, nil
+}
+
+// explicitPaths resolves Config.Files against targetAbs, in the given order
+// and without repeats. Every path must name an existing regular file.
+func explicitPaths(fsys Filesystem, targetAbs, files string) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	for _, f := range SplitClean(files) {
+		p := AbsFrom(targetAbs, f)
+		if seen[p] { continue }
+		seen[p] = true
+		st, err := fsys.Stat(p)
+		if err != nil { return nil, fmt.Errorf("files: %w", err) }
+		if st.IsDir() { return nil, fmt.Errorf("files: %s is a directory", f) }
+		out = append(out, p)
+	}
 	return out, nil
 }
 
+// excluded reports whether path matches one of the Exclude patterns. They
+// are matched against the path relative to targetAbs with a leading slash, and
+// a trailing one for directories, e.g. "/cmd/tool/". A pattern starting with
+// "/" is anchored to that start, so "/cmd/" only matches the top-level cmd
+// directory. One starting with "**/" matches the rest at any depth, so
+// "**/vendor/" matches every vendor directory but not "/myvendor/". Any other
+// pattern may match anywhere, so "cmd" also matches "/internal/cmdutil/x.go".
+func excluded(targetAbs, path string, isDir bool, patterns []string) bool {
+	rel, err := filepath.Rel(targetAbs, path)
+	if err != nil { return false }
+	p := "/" + filepath.ToSlash(rel)
+	if isDir { p += "/" }
+	for _, pat := range patterns {
+		if pat == "" { continue }
+		if rest, ok := strings.CutPrefix(pat, "**/"); ok {
+			if strings.Contains(p, "/"+rest) { return true }
+		} else if strings.HasPrefix(pat, "/") {
+			if strings.HasPrefix(p, pat) { return true }
+		} else if strings.Contains(p, pat) {
+			return true
+		}
+	}
+	return false
+}
+
+// dirDepth returns how many directories deep path is below targetAbs: 1 for
+// a direct subdirectory. The files inside it are one level deeper still.
+func dirDepth(targetAbs, path string) int {
+	rel, err := filepath.Rel(targetAbs, path)
+	if err != nil || rel == "." { return 0 }
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// countDirFiles counts the files with one of exts directly inside dir.
+func countDirFiles(fsys Filesystem, dir string, exts []string) (int, error) {
+	n := 0
+	err := fsys.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil { return err }
+		if d.IsDir() {
+			if path != dir { return filepath.SkipDir }
+			return nil
+		}
+		if endsWithAny(path, exts) { n++ }
+		return nil
+	})
+	return n, err
+}
+
+// endsWithAny reports whether path ends in one of exts; no exts matches every
+// path. Unlike hasExt it matches plain suffixes, so "_test.go" works too.
+func endsWithAny(path string, exts []string) bool {
+	if len(exts) == 0 { return true }
+	for _, e := range exts {
+		if strings.HasSuffix(path, e) { return true }
+	}
+	return false
+}
+
+// containsAny reports whether s contains one of subs.
+func containsAny(s string, subs []string) bool {
+	for _, sub := range subs {
+		if strings.Contains(s, sub) { return true }
+	}
+	return false
+}
+
+// firstPerDir keeps the first of the sorted items in each directory, recording
+// on it how many were dropped.
+func firstPerDir(items []Item, skip func(path, reason string)) []Item {
+	kept := items[:0]
+	first := map[string]int{} // dir -> index in kept
+	for _, it := range items {
+		dir := filepath.Dir(it.abs)
+		if i, ok := first[dir]; ok {
+			kept[i].dirOmitted++
+			skip(it.abs, "one-per-dir")
+			continue
+		}
+		first[dir] = len(kept)
+		kept = append(kept, it)
+	}
+	return kept
+}
+
+// collected is the outcome of reading one candidate file in collect.
+type collected struct {
+	item    Item
+	err     error  // the file could not be read, without FailFast
+	skip    string // skip reason, "" to keep the file
+	longest int    // longest line, when Config.WarnLineLength is set
+}
+
+// newItem stats and hashes the file at path, recording it relative to base.
+func newItem(fsys Filesystem, path, base string) (Item, error) {
+	it, _, err := readItem(fsys, path, base)
+	return it, err
+}
+
+// readItem is newItem that also returns the file content.
+func readItem(fsys Filesystem, path, base string) (Item, []byte, error) {
+	it, err := statItem(fsys, path, base)
+	if err != nil { return Item{}, nil, err }
+	data, err := fsys.ReadFile(path)
+	if err != nil { return Item{}, nil, err }
+	sum := sha256.Sum256(data)
+	it.sha = hex.EncodeToString(sum[:])
+	return it, data, nil
+}
+
+// statItem is newItem without reading the file: the item has no sha.
+func statItem(fsys Filesystem, path, base string) (Item, error) {
+	st, err := fsys.Stat(path)
+	if err != nil { return Item{}, err }
+	rel, _ := filepath.Rel(base, path)
+	return Item{
+		rel:   filepath.ToSlash(rel),
+		abs:   path,
+		size:  st.Size(),
+		mode:  st.Mode().Perm(),
+		mtime: st.ModTime(),
+		fs:    fsys,
+	}, nil
+}
+
+// read returns the file's current content from the filesystem it was collected from.
+func (it Item) read() ([]byte, error) {
+	if it.fs == nil { return os.ReadFile(it.abs) }
+	return it.fs.ReadFile(it.abs)
+}
+
+// resolved returns path with symlinks evaluated, falling back to path itself.
+func resolved(path string) string {
+	if rp, err := filepath.EvalSymlinks(path); err == nil { return rp }
+	return path
+}
+
 // SplitClean splits a comma-separated list and trims/normalizes separators.
 func SplitClean(s string) []string {
 	parts := strings.Split(s, ",")
@@ -154,6 +1502,55 @@ func SplitClean(s string) []string {
 	return out
 }
 
+// hasExt reports whether path ends with one of the comma-separated extensions
+// (case-insensitive; the leading dot is optional).
+func hasExt(path, exts string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range SplitClean(exts) {
+		if !strings.HasPrefix(e, ".") { e = "." + e }
+		if strings.EqualFold(e, ext) { return true }
+	}
+	return false
+}
+
+// RootPlaceholder replaces the scan root in file content under Config.RelativizePaths.
+const RootPlaceholder = "${ROOT}"
+
+// RelativizePaths rewrites every occurrence of the absolute directory root in
+// src (in native or slash form) to RootPlaceholder and reports how many were
+// replaced. A match must end at a path boundary, so "/home/al" does not match
+// inside "/home/alice".
+func RelativizePaths(src []byte, root string) ([]byte, int) {
+	n := 0
+	forms := []string{root}
+	if slash := filepath.ToSlash(root); slash != root { forms = append(forms, slash) }
+	for _, form := range forms {
+		if form == "" || form == "/" { continue }
+		var out []byte
+		rest := src
+		for {
+			i := bytes.Index(rest, []byte(form))
+			if i < 0 { break }
+			end := i + len(form)
+			if end < len(rest) && isPathChar(rest[end]) {
+				out = append(out, rest[:end]...)
+				rest = rest[end:]
+				continue
+			}
+			out = append(append(out, rest[:i]...), RootPlaceholder...)
+			rest = rest[end:]
+			n++
+		}
+		if out != nil { src = append(out, rest...) }
+	}
+	return src, n
+}
+
+// isPathChar reports whether b can continue a path element.
+func isPathChar(b byte) bool {
+	return b == '_' || b == '-' || b == '.' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
+
 // StripPackageLine removes the first "package" line from a Go source file.
 func StripPackageLine(src []byte) []byte {
 	lines := bytes.Split(src, []byte("\n"))
@@ -170,74 +1567,34 @@ func StripPackageLine(src []byte) []byte {
 	return bytes.Join(out, []byte("\n"))
 }
 
-// WriteDefaultRC writes a new RC file with defaults to the given path.
-func WriteDefaultRC(path string) error {
-	content := `# .codedumprc
-# Root of the project (where the final TXT will be saved)
-root=.
-
-# Target folder to scan
-target=./models
-
-# Output file name (relative to root)
-out=models_tree.txt
-
-# File extension to include
-ext=.go
-
-# Substrings to exclude (comma separated)
-exclude=_test.go,/.git/,/vendor/
-
-# Required substring (optional)
-include=
-
-# Keep "package" line (true/false)
-pkg=false
-`
-	return os.WriteFile(path, []byte(content), 0o644)
+// CollapseBlankRuns replaces every run of two or more blank (whitespace-only)
+// lines with a single empty line.
+func CollapseBlankRuns(src []byte) []byte {
+	lines := bytes.Split(src, []byte("\n"))
+	out := make([][]byte, 0, len(lines))
+	prevBlank := false
+	for i, ln := range lines {
+		blank := len(bytes.TrimSpace(ln)) == 0
+		if blank && i == len(lines)-1 {
+			// Keep the final newline (or its absence) as it was.
+			out = append(out, ln)
+			break
+		}
+		if blank && prevBlank { continue }
+		if blank { ln = ln[:0] }
+		out = append(out, ln)
+		prevBlank = blank
+	}
+	return bytes.Join(out, []byte("\n"))
 }
 
-// ReadRC populates the given Config from a RC file.
-func ReadRC(path string, c *Config) error {
-	b, err := os.ReadFile(path)
-	if err != nil { return err }
-	lines := strings.Split(string(b), "\n")
-	for _, ln := range lines {
-		ln = strings.TrimSpace(ln)
-		if ln == "" || strings.HasPrefix(ln, "#") { continue }
-		kv := strings.SplitN(ln, "=", 2)
-		if len(kv) != 2 { continue }
-		k := strings.TrimSpace(kv[0])
-		v := strings.TrimSpace(kv[1])
-		switch strings.ToLower(k) {
-		case "root": c.Root = v
-		case "target": c.Target = v
-		case "out": c.Out = v
-		case "ext": c.Ext = v
-		case "exclude": c.Exclude = v
-		case "include": c.Include = v
-		case "pkg": c.Pkg = strings.EqualFold(v, "true") || v == "1" || strings.EqualFold(v, "yes")
-		}
-	}
-	return nil
-}
-
-// FindRC searches for a .codedumprc starting from the CWD up to root, then $HOME.
-func FindRC() string {
-	wd, _ := os.Getwd()
-	cur := wd
-	for {
-		rc := filepath.Join(cur, DefaultRCName)
-		if _, err := os.Stat(rc); err == nil { return rc }
-		parent := filepath.Dir(cur)
-		if parent == cur { break }
-		cur = parent
-	}
-	if home, err := os.UserHomeDir(); err == nil {
-		rc := filepath.Join(home, DefaultRCName)
-		if _, err := os.Stat(rc); err == nil { return rc }
-	}
-	return ""
+// LongestLine returns the length in characters of the longest line in data.
+func LongestLine(data []byte) int {
+	max := 0
+	for _, ln := range bytes.Split(data, []byte("\n")) {
+		if n := utf8.RuneCount(bytes.TrimSuffix(ln, []byte("\r"))); n > max { max = n }
+	}
+	return max
 }
 
 // AbsFrom resolves a possibly-relative path against a base directory.
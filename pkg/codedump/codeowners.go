@@ -0,0 +1,125 @@
+package codedump
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Unowned is the owner group of files no CODEOWNERS rule assigns.
+const Unowned = "unowned"
+
+// Codeowners matches paths against the rules of a CODEOWNERS file. As on
+// GitHub, the last matching rule wins.
+type Codeowners struct {
+	rules []ownerRule
+}
+
+type ownerRule struct {
+	re     *regexp.Regexp
+	owners []string
+}
+
+// codeownersLocations are the places GitHub looks for the file, in order.
+var codeownersLocations = []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS"}
+
+// FindCodeowners returns the path of the CODEOWNERS file for the repository
+// at base, or "" if there is none.
+func FindCodeowners(base string) string {
+	for _, loc := range codeownersLocations {
+		p := filepath.Join(base, filepath.FromSlash(loc))
+		if st, err := os.Stat(p); err == nil && !st.IsDir() { return p }
+	}
+	return ""
+}
+
+// ParseCodeowners parses CODEOWNERS content.
+func ParseCodeowners(data []byte) (*Codeowners, error) {
+	co := &Codeowners{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		ln := sc.Text()
+		if i := strings.Index(ln, "#"); i >= 0 { ln = ln[:i] }
+		fields := strings.Fields(ln)
+		if len(fields) == 0 { continue }
+		re, err := regexp.Compile(ownerPatternRegexp(fields[0]))
+		if err != nil { return nil, fmt.Errorf("CODEOWNERS line %d: %w", n, err) }
+		co.rules = append(co.rules, ownerRule{re: re, owners: fields[1:]})
+	}
+	return co, sc.Err()
+}
+
+// Owners returns the owners of rel, a slash-separated path relative to the
+// repository root. A matching rule without owners leaves the path unowned.
+func (co *Codeowners) Owners(rel string) []string {
+	for i := len(co.rules) - 1; i >= 0; i-- {
+		if co.rules[i].re.MatchString(rel) { return co.rules[i].owners }
+	}
+	return nil
+}
+
+// ownerPatternRegexp translates a gitignore-style CODEOWNERS pattern. Patterns
+// with a slash before their end are anchored to the repository root; others
+// match at any depth. A match also covers everything below a directory, except
+// for patterns ending in "/*", which cover only the directory's direct entries.
+func ownerPatternRegexp(p string) string {
+	anchored := strings.Contains(strings.TrimSuffix(p, "/"), "/")
+	dirOnly := strings.HasSuffix(p, "/")
+	shallow := strings.HasSuffix(p, "/*")
+	p = strings.Trim(p, "/")
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored { re.WriteString("(?:.*/)?") }
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case p[i] == '*':
+			re.WriteString("[^/]*")
+		case p[i] == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	switch {
+	case shallow:
+		re.WriteString("$")
+	case dirOnly:
+		re.WriteString("/.*$")
+	default:
+		re.WriteString("(?:/.*)?$")
+	}
+	return re.String()
+}
+
+// ownerGroups maps each item's absolute path to its owner group: its owners
+// joined by spaces, or Unowned. Paths are matched relative to the repository
+// root containing rootAbs (rootAbs itself outside git).
+func ownerGroups(rootAbs string, items []Item) (map[string]string, error) {
+	base := GitRoot(rootAbs)
+	if base == "" { base = rootAbs }
+	path := FindCodeowners(base)
+	if path == "" { return nil, fmt.Errorf("group-by owner: no CODEOWNERS file in %s", base) }
+	data, err := os.ReadFile(path)
+	if err != nil { return nil, err }
+	co, err := ParseCodeowners(data)
+	if err != nil { return nil, err }
+	groups := make(map[string]string, len(items))
+	for _, it := range items {
+		rel, err := filepath.Rel(base, it.abs)
+		if err != nil { return nil, err }
+		g := strings.Join(co.Owners(filepath.ToSlash(rel)), " ")
+		if g == "" { g = Unowned }
+		groups[it.abs] = g
+	}
+	return groups, nil
+}
@@ -0,0 +1,106 @@
+package codedump
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CompareStatus describes how a file on disk relates to a dump.
+type CompareStatus string
+
+const (
+	CompareUnchanged CompareStatus = "unchanged" // on disk with the recorded hash
+	CompareModified  CompareStatus = "modified"  // on disk with a different hash
+	CompareDeleted   CompareStatus = "deleted"   // recorded but missing on disk
+	CompareAdded     CompareStatus = "added"     // on disk and matching the filters, but not recorded
+)
+
+// CompareResult is the outcome for one file.
+type CompareResult struct {
+	RelPath string
+	Status  CompareStatus
+}
+
+// Compare checks a text dump against the current state of dir, the directory
+// its #rel_path entries are relative to ("" = as Restore would pick). Every
+// recorded file is reported unchanged, modified or deleted; files under the
+// dump's target that pass c's filters but were not recorded are reported as
+// added. Results are sorted by path.
+func Compare(dumpPath, dir string, c Config) ([]CompareResult, error) {
+	f, err := os.Open(dumpPath)
+	if err != nil { return nil, err }
+	defer f.Close()
+
+	r := NewDumpReader(f)
+	var entries []*Entry
+	for {
+		e, err := r.Next()
+		if errors.Is(err, io.EOF) { break }
+		if err != nil { return nil, err }
+		entries = append(entries, e)
+	}
+	if dir == "" { dir = restoreBase(dumpPath, r.Header) }
+	dirAbs, err := filepath.Abs(dir)
+	if err != nil { return nil, err }
+
+	var out []CompareResult
+	recorded := map[string]bool{}
+	for _, e := range entries {
+		rel := e.RelPath()
+		recorded[rel] = true
+		res := CompareResult{RelPath: rel}
+		data, err := os.ReadFile(filepath.Join(dirAbs, filepath.FromSlash(rel)))
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			res.Status = CompareDeleted
+		case err != nil:
+			return nil, err
+		case sha256Hex(data) == e.SHA256():
+			res.Status = CompareUnchanged
+		default:
+			res.Status = CompareModified
+		}
+		out = append(out, res)
+	}
+
+	items, err := CollectTargets(compareTargets(dirAbs, r.Header), c)
+	if err != nil { return nil, err }
+	for _, it := range items {
+		rel, err := filepath.Rel(dirAbs, it.abs)
+		if err != nil { return nil, err }
+		rel = filepath.ToSlash(rel)
+		if !recorded[rel] { out = append(out, CompareResult{RelPath: rel, Status: CompareAdded}) }
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RelPath < out[j].RelPath })
+	return out, nil
+}
+
+// compareTargets maps the dump's #target, or each of its #targets, onto
+// dirAbs: a target's position relative to the directory its paths were
+// recorded against is kept. When that cannot be worked out, dirAbs itself is
+// scanned.
+func compareTargets(dirAbs string, header map[string]string) []string {
+	targets := []string{header["target"]}
+	if header["targets"] != "" { targets = strings.Split(header["targets"], ", ") }
+	var base string
+	switch header["paths"] {
+	case PathsRelativeToOut:
+		base = filepath.Dir(header["out"])
+	case PathsRelativeToGit:
+		base = GitRoot(filepath.FromSlash(targets[0]))
+	default:
+		base = header["pwd"]
+	}
+	out := make([]string, len(targets))
+	for i, target := range targets {
+		if target == "" || base == "" { return []string{dirAbs} }
+		rel, err := filepath.Rel(filepath.FromSlash(base), filepath.FromSlash(target))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) { return []string{dirAbs} }
+		out[i] = filepath.Join(dirAbs, rel)
+	}
+	return out
+}
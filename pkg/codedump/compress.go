@@ -0,0 +1,22 @@
+package codedump
+
+import "compress/gzip"
+
+// GzipSize returns the size of data compressed with gzip at the default
+// level. Compared to len(data) it is a rough entropy signal: boilerplate and
+// generated files compress far better than hand-written code.
+func GzipSize(data []byte) int {
+	var n countWriter
+	zw := gzip.NewWriter(&n)
+	zw.Write(data)
+	zw.Close()
+	return int(n)
+}
+
+// countWriter counts the bytes written to it and discards them.
+type countWriter int64
+
+func (n *countWriter) Write(p []byte) (int, error) {
+	*n += countWriter(len(p))
+	return len(p), nil
+}
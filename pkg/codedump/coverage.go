@@ -0,0 +1,93 @@
+package codedump
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CoverageMarker prefixes uncovered statement lines under Config.AnnotateCoverage.
+const CoverageMarker = "!"
+
+// CoverProfile holds the blocks of a "go test -coverprofile" file, keyed by the
+// file name as it appears there (normally import path plus base name).
+type CoverProfile map[string][]coverBlock
+
+type coverBlock struct {
+	startLine, endLine, count int
+}
+
+// ReadCoverProfile parses a coverage profile written by go test.
+func ReadCoverProfile(path string) (CoverProfile, error) {
+	f, err := os.Open(path)
+	if err != nil { return nil, err }
+	defer f.Close()
+	p := CoverProfile{}
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		ln := strings.TrimSpace(sc.Text())
+		if ln == "" || strings.HasPrefix(ln, "mode:") { continue }
+		// name.go:startLine.startCol,endLine.endCol numStmts count
+		colon := strings.LastIndex(ln, ":")
+		fields := strings.Fields(ln[colon+1:])
+		if colon < 0 || len(fields) != 3 { return nil, fmt.Errorf("%s:%d: malformed coverage line", path, n) }
+		start, end, _ := strings.Cut(fields[0], ",")
+		var b coverBlock
+		var errs [3]error
+		b.startLine, errs[0] = strconv.Atoi(strings.Split(start, ".")[0])
+		b.endLine, errs[1] = strconv.Atoi(strings.Split(end, ".")[0])
+		b.count, errs[2] = strconv.Atoi(fields[2])
+		if errs[0] != nil || errs[1] != nil || errs[2] != nil { return nil, fmt.Errorf("%s:%d: malformed coverage line", path, n) }
+		name := ln[:colon]
+		p[name] = append(p[name], b)
+	}
+	return p, sc.Err()
+}
+
+// UncoveredLines returns the lines of file that belong to a never-executed
+// block and to no executed one.
+func (p CoverProfile) UncoveredLines(file string) map[int]bool {
+	blocks := p[file]
+	if blocks == nil { blocks = p["_"+file] } // files outside a module
+	covered := map[int]bool{}
+	for _, b := range blocks {
+		if b.count == 0 { continue }
+		for l := b.startLine; l <= b.endLine; l++ { covered[l] = true }
+	}
+	out := map[int]bool{}
+	for _, b := range blocks {
+		if b.count != 0 { continue }
+		for l := b.startLine; l <= b.endLine; l++ {
+			if !covered[l] { out[l] = true }
+		}
+	}
+	return out
+}
+
+// AnnotateUncovered prefixes the given 1-based lines of src with CoverageMarker,
+// skipping lines that are blank or a lone closing brace.
+func AnnotateUncovered(src []byte, lines map[int]bool) ([]byte, int) {
+	if len(lines) == 0 { return src, 0 }
+	parts := bytes.Split(src, []byte("\n"))
+	n := 0
+	for i, ln := range parts {
+		t := bytes.TrimSpace(ln)
+		if !lines[i+1] || len(t) == 0 || string(t) == "}" { continue }
+		parts[i] = append([]byte(CoverageMarker), ln...)
+		n++
+	}
+	return bytes.Join(parts, []byte("\n")), n
+}
+
+// StripCoverageMarks removes the CoverageMarker that AnnotateUncovered put at
+// the start of lines.
+func StripCoverageMarks(src []byte) []byte {
+	parts := bytes.Split(src, []byte("\n"))
+	for i, ln := range parts {
+		parts[i] = bytes.TrimPrefix(ln, []byte(CoverageMarker))
+	}
+	return bytes.Join(parts, []byte("\n"))
+}
@@ -0,0 +1,110 @@
+package codedump
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"sort"
+)
+
+// DefaultDupeWindow is the block size, in lines, used when DupeWindow is 0.
+const DefaultDupeWindow = 6
+
+// minDupeLineChars is the average number of characters per line a window needs
+// to be considered, so runs of braces and blank lines are not reported.
+const minDupeLineChars = 10
+
+// DupeLocation is where a duplicated block starts.
+type DupeLocation struct {
+	Path string `json:"path"`
+	Line int    `json:"line"` // 1-based
+}
+
+// DupeBlock is a run of lines that appears in more than one place.
+type DupeBlock struct {
+	Lines       int            `json:"lines"`
+	Occurrences []DupeLocation `json:"occurrences"`
+	Preview     string         `json:"preview"` // first line of the block
+}
+
+// DupeReport lists duplicated multi-line blocks across the dumped files.
+type DupeReport struct {
+	Window int          `json:"window"`
+	Blocks []*DupeBlock `json:"blocks"`
+}
+
+// FindDuplicateBlocks hashes every window of `window` consecutive lines
+// (whitespace-trimmed) across items and reports the windows that occur more
+// than once. Overlapping windows that repeat together are merged into one
+// longer block. Windows with little content (blank lines, lone braces) are ignored.
+func FindDuplicateBlocks(items []Item, window int) (*DupeReport, error) {
+	if window <= 0 { window = DefaultDupeWindow }
+	type loc struct{ file, line int }
+	files := make([][][]byte, len(items))
+	hashes := make([][]string, len(items))
+	where := map[string][]loc{}
+	for fi, it := range items {
+		data, err := it.read()
+		if err != nil { return nil, err }
+		lines := bytes.Split(data, []byte("\n"))
+		for i := range lines {
+			lines[i] = bytes.TrimSpace(lines[i])
+		}
+		files[fi] = lines
+		hashes[fi] = make([]string, len(lines))
+		for i := 0; i+window <= len(lines); i++ {
+			win := lines[i : i+window]
+			weight := 0
+			for _, ln := range win {
+				if len(ln) > 1 { weight += len(ln) }
+			}
+			if weight < minDupeLineChars*window { continue }
+			sum := sha256.Sum256(bytes.Join(win, []byte("\n")))
+			h := string(sum[:])
+			hashes[fi][i] = h
+			where[h] = append(where[h], loc{fi, i})
+		}
+	}
+
+	// continues reports whether every occurrence of h is preceded by an
+	// occurrence of prev, i.e. the window is the previous block shifted by one.
+	continues := func(h, prev string) bool {
+		if prev == "" || len(where[h]) != len(where[prev]) { return false }
+		for _, l := range where[h] {
+			if l.line == 0 || hashes[l.file][l.line-1] != prev { return false }
+		}
+		return true
+	}
+
+	report := &DupeReport{Window: window, Blocks: []*DupeBlock{}}
+	blockOf := map[string]*DupeBlock{}
+	for fi := range items {
+		for i, h := range hashes[fi] {
+			if h == "" || len(where[h]) < 2 || blockOf[h] != nil { continue }
+			if i > 0 {
+				if prev := hashes[fi][i-1]; blockOf[prev] != nil && continues(h, prev) {
+					blockOf[h] = blockOf[prev]
+					blockOf[h].Lines++
+					continue
+				}
+			}
+			b := &DupeBlock{Lines: window, Preview: string(files[fi][i])}
+			for _, l := range where[h] {
+				b.Occurrences = append(b.Occurrences, DupeLocation{Path: items[l.file].rel, Line: l.line + 1})
+			}
+			blockOf[h] = b
+			report.Blocks = append(report.Blocks, b)
+		}
+	}
+	sort.SliceStable(report.Blocks, func(i, j int) bool { return report.Blocks[i].Lines > report.Blocks[j].Lines })
+	return report, nil
+}
+
+// writeDupeReport writes the duplicate block report for items as indented JSON.
+func writeDupeReport(fsys Filesystem, path string, items []Item, window int) error {
+	rep, err := FindDuplicateBlocks(items, window)
+	if err != nil { return err }
+	b, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil { return err }
+	return writeFile(fsys, path, append(b, '\n'))
+}
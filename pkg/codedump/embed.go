@@ -0,0 +1,107 @@
+package codedump
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// EmbedPatterns returns the patterns of every //go:embed directive in src.
+func EmbedPatterns(src []byte) []string {
+	var out []string
+	sc := bufio.NewScanner(bytes.NewReader(src))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "//go:embed")
+		if !ok || (rest != "" && rest[0] != ' ' && rest[0] != '\t') { continue }
+		out = append(out, splitEmbedArgs(rest)...)
+	}
+	return out
+}
+
+// splitEmbedArgs splits a directive's arguments, honoring "quoted" and `raw` patterns.
+func splitEmbedArgs(s string) []string {
+	var out []string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		if s[0] == '"' || s[0] == '`' {
+			if q, err := strconv.QuotedPrefix(s); err == nil {
+				if v, err := strconv.Unquote(q); err == nil { out = append(out, v) }
+				s = s[len(q):]
+				continue
+			}
+		}
+		i := strings.IndexAny(s, " \t")
+		if i < 0 { i = len(s) }
+		out = append(out, s[:i])
+		s = s[i:]
+	}
+	return out
+}
+
+// embedFiles expands one //go:embed pattern relative to dir the way the go tool
+// does: directories are walked recursively, skipping names that start with "."
+// or "_" unless the pattern has the "all:" prefix.
+func embedFiles(fsys Filesystem, dir, pattern string) []string {
+	all := false
+	if p, ok := strings.CutPrefix(pattern, "all:"); ok {
+		pattern, all = p, true
+	}
+	depth := strings.Count(pattern, "/")
+	var out []string
+	fsys.WalkDir(dir, func(m string, d fs.DirEntry, err error) error {
+		if err != nil || m == dir { return nil }
+		rel, _ := filepath.Rel(dir, m)
+		rel = filepath.ToSlash(rel)
+		if n := strings.Count(rel, "/"); n < depth { return nil }
+		if ok, _ := path.Match(pattern, rel); !ok {
+			if d.IsDir() { return filepath.SkipDir }
+			return nil
+		}
+		if !d.IsDir() {
+			out = append(out, m)
+			return nil
+		}
+		fsys.WalkDir(m, func(p string, d fs.DirEntry, err error) error {
+			if err != nil { return nil }
+			if p != m && !all && (strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "_")) {
+				if d.IsDir() { return filepath.SkipDir }
+				return nil
+			}
+			if !d.IsDir() { out = append(out, p) }
+			return nil
+		})
+		return filepath.SkipDir
+	})
+	return out
+}
+
+// followEmbeds appends the files embedded by the Go files in items, tagging
+// each with the file that embeds it. Files already present are not repeated.
+func followEmbeds(fsys Filesystem, items []Item, base string) ([]Item, error) {
+	seen := map[string]bool{}
+	for _, it := range items {
+		seen[it.abs] = true
+	}
+	n := len(items)
+	for i := 0; i < n; i++ {
+		it := items[i]
+		if !strings.HasSuffix(it.abs, ".go") { continue }
+		data, err := it.read()
+		if err != nil { return nil, err }
+		for _, pat := range EmbedPatterns(data) {
+			for _, path := range embedFiles(fsys, filepath.Dir(it.abs), pat) {
+				if seen[path] { continue }
+				seen[path] = true
+				emb, err := newItem(fsys, path, base)
+				if err != nil { return nil, err }
+				emb.embeddedBy = it.rel
+				items = append(items, emb)
+			}
+		}
+	}
+	return items, nil
+}
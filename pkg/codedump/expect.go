@@ -0,0 +1,58 @@
+package codedump
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// extCounts counts items per file extension (".go"); files without one count as "(none)".
+func extCounts(items []Item) map[string]int {
+	out := map[string]int{}
+	for _, it := range items {
+		ext := filepath.Ext(it.rel)
+		if ext == "" { ext = "(none)" }
+		out[ext]++
+	}
+	return out
+}
+
+// CheckExpect verifies per-extension file counts against spec, a comma-separated
+// list such as "go>=1,proto>=1". Supported operators are >=, <=, ==, =, > and <.
+// All unmet expectations are reported together.
+func CheckExpect(spec string, items []Item) error {
+	counts := extCounts(items)
+	var failed []string
+	for _, rule := range SplitClean(spec) {
+		ext, op, want, err := parseExpectRule(rule)
+		if err != nil { return err }
+		got := counts[ext]
+		ok := false
+		switch op {
+		case ">=": ok = got >= want
+		case "<=": ok = got <= want
+		case "==", "=": ok = got == want
+		case ">": ok = got > want
+		case "<": ok = got < want
+		}
+		if !ok { failed = append(failed, fmt.Sprintf("%s: expected %s %d, got %d", ext, op, want, got)) }
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("expectation not met: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// parseExpectRule splits "go>=1" into ".go", ">=", 1.
+func parseExpectRule(rule string) (string, string, int, error) {
+	i := strings.IndexAny(rule, "<>=")
+	if i <= 0 { return "", "", 0, fmt.Errorf("invalid expect rule %q (want e.g. go>=1)", rule) }
+	ext, rest := strings.TrimSpace(rule[:i]), rule[i:]
+	op := rest[:1]
+	if len(rest) > 1 && rest[1] == '=' { op = rest[:2] }
+	n, err := strconv.Atoi(strings.TrimSpace(rest[len(op):]))
+	if err != nil { return "", "", 0, fmt.Errorf("invalid count in expect rule %q", rule) }
+	if !strings.HasPrefix(ext, ".") { ext = "." + ext }
+	return ext, op, n, nil
+}
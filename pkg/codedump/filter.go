@@ -0,0 +1,82 @@
+package codedump
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Filter decides whether Collect keeps a candidate file, given its item and
+// content. Filters run on Collect's worker pool, so they must be safe for
+// concurrent use.
+type Filter func(it Item, data []byte) (keep bool, err error)
+
+var (
+	filtersMu sync.RWMutex
+	filters   = map[string]Filter{}
+)
+
+// RegisterFilter makes a filter available under name (as in filters=name).
+// Registering a name twice replaces the earlier filter.
+func RegisterFilter(name string, f Filter) {
+	filtersMu.Lock()
+	defer filtersMu.Unlock()
+	filters[name] = f
+}
+
+// Filters lists the registered filter names.
+func Filters() []string {
+	filtersMu.RLock()
+	defer filtersMu.RUnlock()
+	out := make([]string, 0, len(filters))
+	for name := range filters {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// namedFilter is a registered filter resolved for one Collect.
+type namedFilter struct {
+	name string
+	fn   Filter
+}
+
+// lookupFilters resolves a comma-separated list of filter names, in order.
+func lookupFilters(names string) ([]namedFilter, error) {
+	var out []namedFilter
+	for _, name := range SplitClean(names) {
+		filtersMu.RLock()
+		fn, ok := filters[name]
+		filtersMu.RUnlock()
+		if !ok { return nil, fmt.Errorf("unknown filter %q (available: %v)", name, Filters()) }
+		out = append(out, namedFilter{name, fn})
+	}
+	return out, nil
+}
+
+// LargeFileSize is the size above which the built-in "no-large" filter drops
+// a file.
+const LargeFileSize = 1 << 20
+
+// The built-in filters, registered like any other.
+func init() {
+	RegisterFilter("no-tests", func(it Item, _ []byte) (bool, error) {
+		return !strings.HasSuffix(it.rel, "_test.go"), nil
+	})
+	RegisterFilter("no-binary", func(_ Item, data []byte) (bool, error) {
+		return !IsBinary(data), nil
+	})
+	RegisterFilter("no-large", func(it Item, _ []byte) (bool, error) {
+		return it.size <= LargeFileSize, nil
+	})
+}
+
+// IsBinary reports whether data looks binary: it has a NUL byte in its first
+// 8000 bytes, as git decides.
+func IsBinary(data []byte) bool {
+	if len(data) > 8000 { data = data[:8000] }
+	return bytes.IndexByte(data, 0) >= 0
+}
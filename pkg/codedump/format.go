@@ -0,0 +1,115 @@
+package codedump
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Field is one "#key: value" metadata entry of a dump header or file block.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Header is the generation metadata written at the top of a dump.
+type Header struct {
+	Fields []Field // pwd, generated_at, go_version, ... in order
+}
+
+// Get returns the value of the first field named key.
+func (h Header) Get(key string) string {
+	for _, f := range h.Fields {
+		if f.Key == key { return f.Value }
+	}
+	return ""
+}
+
+// targetNames returns the base name of the header's #target, or of each of
+// its #targets joined by ", ", for titles.
+func (h Header) targetNames() string {
+	targets := h.Get("target")
+	if t := h.Get("targets"); t != "" { targets = t }
+	names := strings.Split(targets, ", ")
+	for i, t := range names {
+		names[i] = path.Base(t)
+	}
+	return strings.Join(names, ", ")
+}
+
+// File is one dumped file as handed to a Formatter.
+type File struct {
+	Rel       string
+	Abs       string // "" when paths are recorded relative to the output
+	Size      int64
+	SHA256    string
+	Mode      fs.FileMode // permission bits of the file
+	Meta      []Field     // extra per-file metadata (embedded_by, similar_to, ...) in order
+	Content   []byte      // content to emit, after transforms
+	Raw       []byte      // the file as read, before transforms
+	NoContent bool        // the block only carries metadata, e.g. a #similar_to reference
+}
+
+// Formatter renders a dump. Dump calls WriteHeader once, WriteFile for every
+// file in order, then WriteFooter. A Formatter may keep state between calls, so
+// a fresh one is created per dump.
+type Formatter interface {
+	WriteHeader(w io.Writer, h Header) error
+	WriteFile(w io.Writer, f File) error
+	WriteFooter(w io.Writer) error
+}
+
+// SectionWriter is implemented by formatters that can render free-form
+// sections between files, such as package intros. Formatters without it
+// simply omit those sections.
+type SectionWriter interface {
+	WriteSection(w io.Writer, title, body string) error
+}
+
+// ConfigurableFormatter is implemented by formatters with settings of their
+// own. Dump calls Configure with the effective Config before WriteHeader.
+type ConfigurableFormatter interface {
+	Configure(c Config) error
+}
+
+// DefaultFormat is the format used when Config.Format is empty.
+const DefaultFormat = "txt"
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]func() Formatter{}
+)
+
+// RegisterFormat makes a formatter available under name (as in format=name).
+// Registering a name twice replaces the earlier formatter.
+func RegisterFormat(name string, newFormatter func() Formatter) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[name] = newFormatter
+}
+
+// NewFormatter returns a fresh formatter for the named format ("" = DefaultFormat).
+func NewFormatter(name string) (Formatter, error) {
+	if name == "" { name = DefaultFormat }
+	formatsMu.RLock()
+	fn, ok := formats[name]
+	formatsMu.RUnlock()
+	if !ok { return nil, fmt.Errorf("unknown format %q (available: %v)", name, Formats()) }
+	return fn(), nil
+}
+
+// Formats lists the registered format names.
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	out := make([]string, 0, len(formats))
+	for name := range formats {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
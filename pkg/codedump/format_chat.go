@@ -0,0 +1,158 @@
+package codedump
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterFormat("chat", func() Formatter { return &chatFormatter{} })
+}
+
+// Values for Config.ChatDelimiter.
+const (
+	ChatDelimiterXML      = "xml"      // <file path="..."> ... </file> (default)
+	ChatDelimiterMarkdown = "markdown" // path line followed by a fenced code block
+)
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatFormatter renders the dump as a chat transcript: a JSON object whose
+// "messages" array holds a system preamble and one user message carrying every
+// file, delimited in a way language models parse reliably.
+type chatFormatter struct {
+	delim    string
+	preamble string
+	target   string
+	files    int
+	body     strings.Builder
+}
+
+func (f *chatFormatter) Configure(c Config) error {
+	switch c.ChatDelimiter {
+	case "", ChatDelimiterXML: f.delim = ChatDelimiterXML
+	case ChatDelimiterMarkdown: f.delim = ChatDelimiterMarkdown
+	default: return fmt.Errorf("chat_delimiter: unknown style %q (want xml or markdown)", c.ChatDelimiter)
+	}
+	f.preamble = c.ChatPreamble
+	return nil
+}
+
+func (f *chatFormatter) WriteHeader(w io.Writer, h Header) error {
+	if f.delim == "" { f.delim = ChatDelimiterXML }
+	f.target = h.targetNames()
+	return nil
+}
+
+// WriteFile adds file to the user message. With the xml delimiter, a file whose
+// content could be taken for a tag of its own, such as a "</file>" line that
+// would end the block early, is written in the markdown form instead, whose
+// fence is always longer than any in the content.
+func (f *chatFormatter) WriteFile(w io.Writer, file File) error {
+	f.files++
+	file.Meta = chatMeta(file.Meta)
+	if f.delim == ChatDelimiterMarkdown || chatTagLike(file.Content) {
+		f.writeFenced(file)
+		return nil
+	}
+	fmt.Fprintf(&f.body, "<file path=\"%s\"", html.EscapeString(file.Rel))
+	for _, m := range file.Meta {
+		fmt.Fprintf(&f.body, " %s=\"%s\"", m.Key, html.EscapeString(m.Value))
+	}
+	if file.NoContent {
+		f.body.WriteString("/>\n\n")
+		return nil
+	}
+	f.body.WriteString(">\n")
+	f.body.Write(file.Content)
+	if len(file.Content) > 0 && file.Content[len(file.Content)-1] != '\n' { f.body.WriteString("\n") }
+	f.body.WriteString("</file>\n\n")
+	return nil
+}
+
+// writeFenced adds file as its path, with the metadata in parentheses,
+// followed by a fenced code block.
+func (f *chatFormatter) writeFenced(file File) {
+	fmt.Fprintf(&f.body, "%s", file.Rel)
+	for _, m := range file.Meta {
+		fmt.Fprintf(&f.body, " (%s: %s)", m.Key, m.Value)
+	}
+	f.body.WriteString("\n")
+	if file.NoContent {
+		f.body.WriteString("\n")
+		return
+	}
+	fence := markdownFence(file.Content)
+	fmt.Fprintf(&f.body, "%s%s\n", fence, MarkdownLang(file.Rel))
+	f.body.Write(file.Content)
+	if len(file.Content) > 0 && file.Content[len(file.Content)-1] != '\n' { f.body.WriteString("\n") }
+	fmt.Fprintf(&f.body, "%s\n\n", fence)
+}
+
+// chatTagLike reports whether content holds a "<file " or "</file>" tag that
+// would break the xml delimiter's framing.
+func chatTagLike(content []byte) bool {
+	return bytes.Contains(content, []byte("</file>")) || bytes.Contains(content, []byte("<file "))
+}
+
+// WriteSection adds free-form sections such as package intros to the user message.
+func (f *chatFormatter) WriteSection(w io.Writer, title, body string) error {
+	if f.delim == ChatDelimiterMarkdown {
+		fmt.Fprintf(&f.body, "## %s\n\n%s\n\n", title, body)
+		return nil
+	}
+	fmt.Fprintf(&f.body, "<section title=\"%s\">\n%s\n</section>\n\n", html.EscapeString(title), body)
+	return nil
+}
+
+func (f *chatFormatter) WriteFooter(w io.Writer) error {
+	preamble := f.preamble
+	if preamble == "" {
+		how := `wrapped in <file path="..."> tags`
+		if f.delim == ChatDelimiterMarkdown { how = "given as its path followed by a fenced code block" }
+		dirs := "directory"
+		if strings.Contains(f.target, ", ") { dirs = "directories" }
+		preamble = fmt.Sprintf("You are reviewing source code from the %q %s. The user message contains %d files, each %s. Paths are relative to the project.", f.target, dirs, f.files, how)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Messages []chatMessage `json:"messages"`
+	}{[]chatMessage{
+		{Role: "system", Content: preamble},
+		{Role: "user", Content: strings.TrimRight(f.body.String(), "\n")},
+	}})
+}
+
+// chatMeta drops metadata that only matters for restoring or verifying a dump. The md
+// format uses it too.
+func chatMeta(meta []Field) []Field {
+	var out []Field
+	for _, m := range meta {
+		if m.Key != "transformed" && m.Key != "sha256_dumped" { out = append(out, m) }
+	}
+	return out
+}
+
+// markdownFence returns a backtick fence longer than any backtick run in content.
+func markdownFence(content []byte) string {
+	longest, run := 0, 0
+	for _, b := range content {
+		if b == '`' {
+			run++
+			if run > longest { longest = run }
+		} else {
+			run = 0
+		}
+	}
+	if longest < 3 { return "```" }
+	return strings.Repeat("`", longest+1)
+}
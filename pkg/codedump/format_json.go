@@ -0,0 +1,75 @@
+package codedump
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+func init() {
+	RegisterFormat("json", func() Formatter { return &jsonFormatter{} })
+}
+
+// jsonFormatter writes one JSON object: the header fields as top-level keys
+// followed by a "files" array. It is written piece by piece, so a dump never
+// has to be held as one value, yet the result is a single valid document.
+type jsonFormatter struct {
+	files int
+}
+
+func (f *jsonFormatter) WriteHeader(w io.Writer, h Header) error {
+	io.WriteString(w, "{\n")
+	for _, fl := range h.Fields {
+		fmt.Fprintf(w, "  %s: %s,\n", jsonString(fl.Key), jsonString(fl.Value))
+	}
+	_, err := io.WriteString(w, `  "files": [`)
+	return err
+}
+
+func (f *jsonFormatter) WriteFile(w io.Writer, file File) error {
+	if f.files > 0 { io.WriteString(w, ",") }
+	f.files++
+	fmt.Fprintf(w, "\n    {\n      \"rel_path\": %s,\n", jsonString(file.Rel))
+	if file.Abs != "" {
+		fmt.Fprintf(w, "      \"abs_path\": %s,\n", jsonString(file.Abs))
+	}
+	fmt.Fprintf(w, "      \"size_bytes\": %d,\n      \"sha256\": %s", file.Size, jsonString(file.SHA256))
+	if len(file.Meta) > 0 {
+		io.WriteString(w, ",\n      \"meta\": {")
+		for i, m := range file.Meta {
+			if i > 0 { io.WriteString(w, ", ") }
+			fmt.Fprintf(w, "%s: %s", jsonString(m.Key), jsonString(m.Value))
+		}
+		io.WriteString(w, "}")
+	}
+	switch {
+	case file.NoContent:
+	case utf8.Valid(file.Content):
+		fmt.Fprintf(w, ",\n      \"content\": %s", jsonString(string(file.Content)))
+	default:
+		// A JSON string cannot carry these bytes, so they go base64-encoded
+		// rather than with U+FFFD in place of the invalid ones.
+		fmt.Fprintf(w, ",\n      \"content_base64\": %s", jsonString(base64.StdEncoding.EncodeToString(file.Content)))
+	}
+	_, err := io.WriteString(w, "\n    }")
+	return err
+}
+
+func (f *jsonFormatter) WriteFooter(w io.Writer) error {
+	if f.files > 0 { io.WriteString(w, "\n  ") }
+	_, err := io.WriteString(w, "]\n}\n")
+	return err
+}
+
+// jsonString encodes s as a JSON string, leaving <, > and & unescaped. Invalid
+// UTF-8 is replaced with U+FFFD.
+func jsonString(s string) []byte {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
@@ -0,0 +1,94 @@
+package codedump
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+func init() {
+	RegisterFormat("md", func() Formatter { return mdFormatter{} })
+}
+
+// mdFormatter writes Markdown: a "## path" heading per file, its metadata as a
+// short list, and the content in a fenced code block tagged with its language.
+// It renders well in chat tools and on code hosts but is not read back by
+// DumpReader.
+type mdFormatter struct{}
+
+func (mdFormatter) WriteHeader(w io.Writer, h Header) error {
+	fmt.Fprintf(w, "# codeDump: %s\n\n", h.targetNames())
+	for _, f := range h.Fields {
+		fmt.Fprintf(w, "- %s: `%s`\n", f.Key, f.Value)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (mdFormatter) WriteFile(w io.Writer, f File) error {
+	fmt.Fprintf(w, "## %s\n\n", f.Rel)
+	if f.Abs != "" {
+		fmt.Fprintf(w, "- abs_path: `%s`\n", f.Abs)
+	}
+	fmt.Fprintf(w, "- size: %d bytes\n", f.Size)
+	fmt.Fprintf(w, "- sha256: `%s`\n", f.SHA256)
+	for _, m := range chatMeta(f.Meta) {
+		fmt.Fprintf(w, "- %s: %s\n", m.Key, m.Value)
+	}
+	io.WriteString(w, "\n")
+	if f.NoContent { return nil }
+	fence := markdownFence(f.Content)
+	fmt.Fprintf(w, "%s%s\n", fence, MarkdownLang(f.Rel))
+	w.Write(f.Content)
+	if len(f.Content) > 0 && f.Content[len(f.Content)-1] != '\n' {
+		io.WriteString(w, "\n")
+	}
+	_, err := fmt.Fprintf(w, "%s\n\n", fence)
+	return err
+}
+
+func (mdFormatter) WriteFooter(w io.Writer) error { return nil }
+
+// WriteSection writes a "## TITLE" heading followed by body as a quote.
+func (mdFormatter) WriteSection(w io.Writer, title, body string) error {
+	fmt.Fprintf(w, "## %s\n\n", title)
+	for _, ln := range strings.Split(body, "\n") {
+		if ln == "" {
+			io.WriteString(w, ">\n")
+			continue
+		}
+		fmt.Fprintf(w, "> %s\n", ln)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// markdownLangs maps extensions to the fenced code block language names
+// highlighters know, where the two differ.
+var markdownLangs = map[string]string{
+	".py":    "python",
+	".js":    "javascript",
+	".mjs":   "javascript",
+	".ts":    "typescript",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".kt":    "kotlin",
+	".cs":    "csharp",
+	".sh":    "bash",
+	".yml":   "yaml",
+	".md":    "markdown",
+	".h":     "c",
+	".hpp":   "cpp",
+	".cc":    "cpp",
+	".mod":   "go-mod",
+	".proto": "protobuf",
+}
+
+// MarkdownLang returns the fenced code block language hint for path: the
+// extension itself (go, sql, ...) unless markdownLangs knows a better name.
+func MarkdownLang(p string) string {
+	ext := strings.ToLower(path.Ext(p))
+	if lang, ok := markdownLangs[ext]; ok { return lang }
+	return strings.TrimPrefix(ext, ".")
+}
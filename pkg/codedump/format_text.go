@@ -0,0 +1,63 @@
+package codedump
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterFormat("txt", func() Formatter { return textFormatter{} })
+}
+
+// textFormatter writes the default comment-banner format read back by DumpReader.
+type textFormatter struct{}
+
+func (textFormatter) WriteHeader(w io.Writer, h Header) error {
+	fmt.Fprintf(w, "%s\n", markHeaderBegin)
+	for _, f := range h.Fields {
+		fmt.Fprintf(w, "// #%s: %s\n", f.Key, f.Value)
+	}
+	_, err := fmt.Fprintf(w, "%s\n\n", markHeaderEnd)
+	return err
+}
+
+func (textFormatter) WriteFile(w io.Writer, f File) error {
+	fmt.Fprintf(w, "%s\n", markFileBegin)
+	fmt.Fprintf(w, "// #rel_path: %s\n", f.Rel)
+	if f.Abs != "" {
+		fmt.Fprintf(w, "// #abs_path: %s\n", f.Abs)
+	}
+	fmt.Fprintf(w, "// #size_bytes: %d\n", f.Size)
+	fmt.Fprintf(w, "// #sha256: %s\n", f.SHA256)
+	for _, m := range f.Meta {
+		fmt.Fprintf(w, "// #%s: %s\n", m.Key, m.Value)
+	}
+	fmt.Fprintf(w, "%s\n", markMetaEnd)
+	if !f.NoContent {
+		content := EscapeContent(f.Content)
+		w.Write(content)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			io.WriteString(w, "\n")
+		}
+	}
+	_, err := fmt.Fprintf(w, "%s\n\n", markFileEnd)
+	return err
+}
+
+func (textFormatter) WriteFooter(w io.Writer) error { return nil }
+
+// WriteSection writes a "// ===== TITLE =====" banner followed by body as
+// comment lines. DumpReader skips these sections.
+func (textFormatter) WriteSection(w io.Writer, title, body string) error {
+	fmt.Fprintf(w, "// ===== %s =====\n", title)
+	for _, ln := range strings.Split(body, "\n") {
+		if ln == "" {
+			io.WriteString(w, "//\n")
+			continue
+		}
+		fmt.Fprintf(w, "// %s\n", ln)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
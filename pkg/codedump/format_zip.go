@@ -0,0 +1,69 @@
+package codedump
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+func init() {
+	RegisterFormat("zip", func() Formatter { return &zipFormatter{} })
+}
+
+// ZipManifestName is the archive entry describing a zip dump.
+const ZipManifestName = "codedump-manifest.json"
+
+type zipManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// zipFormatter writes the original files, untransformed and with their
+// permission bits, into a zip archive under their #rel_path, plus a manifest
+// of the header fields and file hashes.
+type zipFormatter struct {
+	zw     *zip.Writer
+	header map[string]string
+	files  []zipManifestFile
+}
+
+func (f *zipFormatter) WriteHeader(w io.Writer, h Header) error {
+	f.zw = zip.NewWriter(w)
+	f.header = make(map[string]string, len(h.Fields))
+	for _, fl := range h.Fields {
+		f.header[fl.Key] = fl.Value
+	}
+	return nil
+}
+
+func (f *zipFormatter) WriteFile(w io.Writer, file File) error {
+	name := path.Clean(file.Rel)
+	if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+		return fmt.Errorf("zip: %s lies outside the archive root; use paths=cwd or relative-to-git", file.Rel)
+	}
+	zh := &zip.FileHeader{Name: name, Method: zip.Deflate}
+	zh.SetMode(file.Mode)
+	zf, err := f.zw.CreateHeader(zh)
+	if err != nil { return err }
+	if _, err := zf.Write(file.Raw); err != nil { return err }
+	f.files = append(f.files, zipManifestFile{Path: name, Size: file.Size, SHA256: file.SHA256})
+	return nil
+}
+
+func (f *zipFormatter) WriteFooter(w io.Writer) error {
+	b, err := json.MarshalIndent(struct {
+		Header map[string]string  `json:"header"`
+		Files  []zipManifestFile `json:"files"`
+	}{f.header, f.files}, "", "  ")
+	if err != nil { return err }
+	zh := &zip.FileHeader{Name: ZipManifestName, Method: zip.Deflate}
+	zh.SetMode(0o644)
+	zf, err := f.zw.CreateHeader(zh)
+	if err != nil { return err }
+	if _, err := zf.Write(append(b, '\n')); err != nil { return err }
+	return f.zw.Close()
+}
@@ -0,0 +1,116 @@
+package codedump
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing/fstest"
+	"time"
+)
+
+// Filesystem is the file access Dump and Collect go through: walking and
+// reading the target, and writing the dump and its sidecar files. Set
+// Config.FS to swap in another implementation, such as a MemFS in tests.
+//
+// Features that inspect the surrounding repository or toolchain (git, go.mod
+// lookups, package docs, CODEOWNERS) and checkpoints always use the OS.
+type Filesystem interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	Stat(name string) (fs.FileInfo, error)
+	WalkDir(root string, fn fs.WalkDirFunc) error
+	MkdirAll(path string, perm fs.FileMode) error
+}
+
+// OSFS is the Filesystem backed by the os package, used when Config.FS is nil.
+type OSFS struct{}
+
+func (OSFS) ReadFile(name string) ([]byte, error)                    { return os.ReadFile(name) }
+func (OSFS) WriteFile(name string, data []byte, perm fs.FileMode) error { return os.WriteFile(name, data, perm) }
+func (OSFS) Stat(name string) (fs.FileInfo, error)                   { return os.Stat(name) }
+func (OSFS) WalkDir(root string, fn fs.WalkDirFunc) error            { return filepath.WalkDir(root, fn) }
+func (OSFS) MkdirAll(path string, perm fs.FileMode) error            { return os.MkdirAll(path, perm) }
+
+// MemFS is an in-memory Filesystem keyed by absolute slash-separated paths,
+// meant for tests that assert exact Dump output without touching disk.
+// Directories exist implicitly above every file. It is safe for concurrent use.
+type MemFS struct {
+	mu    sync.Mutex
+	files fstest.MapFS
+}
+
+// NewMemFS returns a MemFS holding files, keyed by absolute path.
+func NewMemFS(files map[string]string) *MemFS {
+	m := &MemFS{files: fstest.MapFS{}}
+	for name, data := range files {
+		m.files[memKey(name)] = &fstest.MapFile{Data: []byte(data), Mode: 0o644}
+	}
+	return m
+}
+
+// memKey turns an absolute path into a MapFS key ("." for the root).
+func memKey(name string) string {
+	k := strings.TrimPrefix(filepath.ToSlash(filepath.Clean(name)), "/")
+	if k == "" { return "." }
+	return k
+}
+
+// memPath is the inverse of memKey.
+func memPath(key string) string {
+	if key == "." { return string(filepath.Separator) }
+	return filepath.FromSlash("/" + key)
+}
+
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, err := m.files.ReadFile(memKey(name))
+	if err != nil { return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist} }
+	return data, nil
+}
+
+func (m *MemFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[memKey(name)] = &fstest.MapFile{Data: append([]byte(nil), data...), Mode: perm, ModTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) Stat(name string) (fs.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.files.Stat(memKey(name))
+}
+
+func (m *MemFS) MkdirAll(path string, perm fs.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := memKey(path)
+	if f, ok := m.files[k]; ok && !f.Mode.IsDir() { return &fs.PathError{Op: "mkdir", Path: path, Err: fs.ErrExist} }
+	if k != "." { m.files[k] = &fstest.MapFile{Mode: fs.ModeDir | perm} }
+	return nil
+}
+
+// WalkDir walks a snapshot of the tree, so fn may write to m.
+func (m *MemFS) WalkDir(root string, fn fs.WalkDirFunc) error {
+	m.mu.Lock()
+	snap := make(fstest.MapFS, len(m.files))
+	for k, f := range m.files { snap[k] = f }
+	m.mu.Unlock()
+	return fs.WalkDir(snap, memKey(root), func(key string, d fs.DirEntry, err error) error {
+		return fn(memPath(key), d, err)
+	})
+}
+
+// Files returns a copy of every file in m keyed by absolute slash path.
+func (m *MemFS) Files() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]string, len(m.files))
+	for k, f := range m.files {
+		if !f.Mode.IsDir() { out["/"+k] = string(f.Data) }
+	}
+	return out
+}
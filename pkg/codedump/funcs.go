@@ -0,0 +1,219 @@
+package codedump
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+)
+
+// FilterFuncs reduces Go source to its package clause and the top-level
+// function and method declarations (with their doc comments) whose names match
+// one of the glob patterns, e.g. "ServeHTTP" or "Handle*". The declarations keep
+// their original formatting. Source that does not parse is returned unchanged.
+func FilterFuncs(src []byte, patterns []string) []byte {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil { return src }
+	off := func(p token.Pos) int { return fset.Position(p).Offset }
+
+	var buf bytes.Buffer
+	buf.Write(src[off(f.Package):off(f.Name.End())])
+	buf.WriteString("\n")
+	for _, d := range f.Decls {
+		fd, ok := d.(*ast.FuncDecl)
+		if !ok || !matchAny(fd.Name.Name, patterns) { continue }
+		start := fd.Pos()
+		if fd.Doc != nil { start = fd.Doc.Pos() }
+		buf.WriteString("\n")
+		buf.Write(src[off(start):off(fd.End())])
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// FilterStructs reduces Go source to its package clause and the struct type
+// definitions, fields and tags included, with their doc comments. Methods,
+// functions and other declarations are dropped, leaving a compact schema of a
+// data model. Source without struct types yields nothing; source that does not
+// parse is returned unchanged.
+func FilterStructs(src []byte) []byte {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil { return src }
+	off := func(p token.Pos) int { return fset.Position(p).Offset }
+
+	var buf bytes.Buffer
+	for _, d := range f.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE { continue }
+		for _, spec := range gd.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if _, ok := ts.Type.(*ast.StructType); !ok { continue }
+			if buf.Len() == 0 {
+				buf.Write(src[off(f.Package):off(f.Name.End())])
+				buf.WriteString("\n")
+			}
+			buf.WriteString("\n")
+			if !gd.Lparen.IsValid() {
+				start := gd.Pos()
+				if gd.Doc != nil { start = gd.Doc.Pos() }
+				buf.Write(src[off(start):off(gd.End())])
+			} else {
+				// One spec of a grouped "type ( ... )" declaration.
+				if ts.Doc != nil {
+					buf.Write(src[off(ts.Doc.Pos()):off(ts.Doc.End())])
+					buf.WriteString("\n")
+				}
+				buf.WriteString("type ")
+				buf.Write(src[off(ts.Pos()):off(ts.End())])
+			}
+			buf.WriteString("\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// ExtractDocs reduces Go source to its doc comments: the package doc and
+// clause, then every documented top-level declaration as its doc comment and
+// signature. Functions lose their bodies, struct and interface types keep only
+// their documented fields and methods, and constants and variables lose their
+// values. In a documented "const ( ... )" group every spec is kept. Source that
+// does not parse is returned unchanged.
+func ExtractDocs(src []byte) []byte {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil { return src }
+	off := func(p token.Pos) int { return fset.Position(p).Offset }
+	text := func(from, to token.Pos) []byte { return src[off(from):off(to)] }
+
+	var buf bytes.Buffer
+	writeDoc(&buf, f.Doc, "")
+	buf.Write(text(f.Package, f.Name.End()))
+	buf.WriteString("\n")
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			if d.Doc == nil { continue }
+			buf.WriteString("\n")
+			writeDoc(&buf, d.Doc, "")
+			buf.Write(text(d.Pos(), d.Type.End()))
+			buf.WriteString("\n")
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT { continue }
+			if !d.Lparen.IsValid() {
+				if len(d.Specs) == 0 || (d.Doc == nil && !hasFieldDocs(d.Specs[0])) { continue }
+				buf.WriteString("\n")
+				writeDoc(&buf, d.Doc, "")
+				buf.WriteString(d.Tok.String() + " ")
+				writeSpecDocs(&buf, d.Specs[0], "", text)
+				continue
+			}
+			var specs []ast.Spec
+			for _, spec := range d.Specs {
+				if d.Doc != nil || specDoc(spec) != nil || hasFieldDocs(spec) { specs = append(specs, spec) }
+			}
+			if len(specs) == 0 { continue }
+			buf.WriteString("\n")
+			writeDoc(&buf, d.Doc, "")
+			buf.WriteString(d.Tok.String() + " (\n")
+			for _, spec := range specs {
+				writeDoc(&buf, specDoc(spec), "\t")
+				buf.WriteString("\t")
+				writeSpecDocs(&buf, spec, "\t", text)
+			}
+			buf.WriteString(")\n")
+		}
+	}
+	return buf.Bytes()
+}
+
+// writeSpecDocs writes the signature of a type, const or var spec for
+// ExtractDocs, with the documented fields or methods of struct and interface
+// types. indent is the indentation of the spec itself.
+func writeSpecDocs(buf *bytes.Buffer, spec ast.Spec, indent string, text func(from, to token.Pos) []byte) {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		var fields *ast.FieldList
+		switch t := s.Type.(type) {
+		case *ast.StructType: fields = t.Fields
+		case *ast.InterfaceType: fields = t.Methods
+		default:
+			buf.Write(text(s.Pos(), s.End()))
+			buf.WriteString("\n")
+			return
+		}
+		buf.Write(text(s.Pos(), s.Type.Pos()))
+		if _, ok := s.Type.(*ast.StructType); ok {
+			buf.WriteString("struct")
+		} else {
+			buf.WriteString("interface")
+		}
+		if !hasFieldDocs(spec) {
+			buf.WriteString("\n")
+			return
+		}
+		buf.WriteString(" {\n")
+		for _, fd := range fields.List {
+			if fd.Doc == nil { continue }
+			writeDoc(buf, fd.Doc, indent+"\t")
+			buf.WriteString(indent + "\t")
+			buf.Write(text(fd.Pos(), fd.Type.End()))
+			buf.WriteString("\n")
+		}
+		buf.WriteString(indent + "}\n")
+	case *ast.ValueSpec:
+		for i, n := range s.Names {
+			if i > 0 { buf.WriteString(", ") }
+			buf.WriteString(n.Name)
+		}
+		if s.Type != nil {
+			buf.WriteString(" ")
+			buf.Write(text(s.Type.Pos(), s.Type.End()))
+		}
+		buf.WriteString("\n")
+	}
+}
+
+// matchAny reports whether name matches any of the glob patterns.
+func matchAny(name string, patterns []string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok { return true }
+	}
+	return false
+}
+
+// specDoc returns the doc comment of a spec inside a grouped declaration.
+func specDoc(spec ast.Spec) *ast.CommentGroup {
+	switch s := spec.(type) {
+	case *ast.TypeSpec: return s.Doc
+	case *ast.ValueSpec: return s.Doc
+	}
+	return nil
+}
+
+// hasFieldDocs reports whether spec is a struct or interface type with at
+// least one documented field or method.
+func hasFieldDocs(spec ast.Spec) bool {
+	ts, ok := spec.(*ast.TypeSpec)
+	if !ok { return false }
+	var fields *ast.FieldList
+	switch t := ts.Type.(type) {
+	case *ast.StructType: fields = t.Fields
+	case *ast.InterfaceType: fields = t.Methods
+	default: return false
+	}
+	for _, fd := range fields.List {
+		if fd.Doc != nil { return true }
+	}
+	return false
+}
+
+// writeDoc writes each comment of doc on its own line, indented.
+func writeDoc(buf *bytes.Buffer, doc *ast.CommentGroup, indent string) {
+	if doc == nil { return }
+	for _, c := range doc.List {
+		buf.WriteString(indent + c.Text + "\n")
+	}
+}
@@ -0,0 +1,128 @@
+package codedump
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// gitOutput runs git with args inside dir and returns trimmed stdout.
+func gitOutput(dir string, args ...string) (string, error) {
+	out, err := gitRaw(dir, args...)
+	return strings.TrimSpace(out), err
+}
+
+// gitNames runs git with args, which must ask for NUL-terminated paths (-z),
+// inside dir and returns those paths exactly as git wrote them, unquoted.
+func gitNames(dir string, args ...string) ([]string, error) {
+	out, err := gitRaw(dir, args...)
+	if err != nil { return nil, err }
+	var names []string
+	for _, n := range strings.Split(out, "\x00") {
+		if n != "" { names = append(names, n) }
+	}
+	return names, nil
+}
+
+// gitRaw runs git with args inside dir and returns its stdout.
+func gitRaw(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" { msg = err.Error() }
+		return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), msg)
+	}
+	return string(out), nil
+}
+
+// GitRoot returns the closest directory at or above dir that contains a .git
+// entry (directory or file, as in worktrees and submodules), or "" if none.
+func GitRoot(dir string) string {
+	for cur := dir; ; {
+		if _, err := os.Stat(filepath.Join(cur, ".git")); err == nil { return cur }
+		parent := filepath.Dir(cur)
+		if parent == cur { return "" }
+		cur = parent
+	}
+}
+
+// DefaultBranch guesses the repository's default branch: origin/HEAD when it is
+// set, otherwise a local "main" or "master".
+func DefaultBranch(dir string) (string, error) {
+	if ref, err := gitOutput(dir, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil && ref != "" {
+		return ref, nil
+	}
+	for _, b := range []string{"main", "master"} {
+		if _, err := gitOutput(dir, "rev-parse", "--verify", "--quiet", b+"^{commit}"); err == nil {
+			return b, nil
+		}
+	}
+	return "", errors.New("could not determine the default branch (no origin/HEAD, main or master); set base_branch")
+}
+
+// BranchDiffFiles returns the absolute paths of files changed since the merge
+// base of HEAD and base, including uncommitted changes and untracked files that
+// are not ignored. Deleted files are omitted. An empty base means the default
+// branch.
+func BranchDiffFiles(dir, base string) (map[string]bool, error) {
+	top, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil { return nil, fmt.Errorf("branch diff needs a git repository: %w", err) }
+	if _, err := gitOutput(dir, "rev-parse", "--verify", "--quiet", "HEAD^{commit}"); err != nil {
+		return nil, errors.New("branch diff: HEAD does not point to a commit")
+	}
+	if base == "" {
+		if base, err = DefaultBranch(dir); err != nil { return nil, err }
+	}
+	if _, err := gitOutput(dir, "rev-parse", "--verify", "--quiet", base+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("branch diff: base branch %q not found", base)
+	}
+	mb, err := gitOutput(dir, "merge-base", "HEAD", base)
+	if err != nil {
+		return nil, fmt.Errorf("branch diff: HEAD and %q share no history (detached or unrelated HEAD?): %w", base, err)
+	}
+	// Both list paths relative to top: diff always, ls-files when run there.
+	changed, err := gitNames(dir, "diff", "-z", "--no-relative", "--name-only", "--diff-filter=d", mb)
+	if err != nil { return nil, err }
+	untracked, err := gitNames(top, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil { return nil, err }
+	out := map[string]bool{}
+	for _, n := range append(changed, untracked...) {
+		out[filepath.Join(top, filepath.FromSlash(n))] = true
+	}
+	return out, nil
+}
+
+// LastCommitTimes returns, for every file touched by the history of the
+// repository containing dir, the time of the last commit that changed it,
+// keyed by absolute path. Files never committed are absent.
+func LastCommitTimes(dir string) (map[string]time.Time, error) {
+	top, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil { return nil, err }
+	log, err := gitOutput(dir, "log", "--format=@%ct", "--name-only", "--no-renames", "--", ".")
+	if err != nil { return nil, err }
+	out := map[string]time.Time{}
+	var cur time.Time
+	for _, ln := range strings.Split(log, "\n") {
+		switch {
+		case ln == "":
+		case strings.HasPrefix(ln, "@"):
+			sec, err := strconv.ParseInt(ln[1:], 10, 64)
+			if err != nil { return nil, fmt.Errorf("git log: bad timestamp %q", ln) }
+			cur = time.Unix(sec, 0)
+		default:
+			p := filepath.Join(top, filepath.FromSlash(ln))
+			if _, seen := out[p]; !seen { out[p] = cur }
+		}
+	}
+	return out, nil
+}
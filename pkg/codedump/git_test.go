@@ -0,0 +1,61 @@
+package codedump
+
+import (
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// gitRun runs git with args in dir, failing the test on error.
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil { t.Fatalf("git %v: %v\n%s", args, err, out) }
+}
+
+func TestBranchDiffFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil { t.Skip("git not installed") }
+	// git reports the resolved top-level directory.
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil { t.Fatal(err) }
+	gitRun(t, dir, "init", "-q", "-b", "main")
+	writeFiles(t, dir, map[string]string{
+		".gitignore":   "*.log\n",
+		"same.go":      "package p\n",
+		"edited.go":    "package p\n",
+		"deleted.go":   "package p\n",
+		"sub/dirty.go": "package sub\n",
+	})
+	gitRun(t, dir, "add", "-A")
+	gitRun(t, dir, "commit", "-q", "-m", "base")
+	gitRun(t, dir, "checkout", "-q", "-b", "feature")
+	writeFiles(t, dir, map[string]string{
+		"edited.go":        "package p\n\nvar x = 1\n",
+		"committed new.go": "package p\n",
+	})
+	gitRun(t, dir, "rm", "-q", "deleted.go")
+	gitRun(t, dir, "add", "-A")
+	gitRun(t, dir, "commit", "-q", "-m", "feature")
+	writeFiles(t, dir, map[string]string{
+		"sub/dirty.go":     "package sub\n\nvar y = 2\n",
+		"sub/untracked.go": "package sub\n",
+		"sub/ünïcode.go":   "package sub\n",
+		"debug.log":        "ignored\n",
+	})
+
+	// Run from a subdirectory: every path still counts from the top.
+	got, err := BranchDiffFiles(filepath.Join(dir, "sub"), "main")
+	if err != nil { t.Fatal(err) }
+	var rels []string
+	for p := range got {
+		rel, err := filepath.Rel(dir, p)
+		if err != nil { t.Fatal(err) }
+		rels = append(rels, filepath.ToSlash(rel))
+	}
+	sort.Strings(rels)
+	want := []string{"committed new.go", "edited.go", "sub/dirty.go", "sub/untracked.go", "sub/ünïcode.go"}
+	if !reflect.DeepEqual(rels, want) { t.Errorf("got %q, want %q", rels, want) }
+}
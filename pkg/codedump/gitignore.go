@@ -0,0 +1,133 @@
+package codedump
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// gitignore matches paths against the .gitignore files of a tree. Rules are
+// kept in load order, parents before children, and as in git the last
+// matching rule decides.
+type gitignore struct {
+	rules []ignoreRule
+}
+
+// ignoreRule is one .gitignore pattern, relative to the directory of its file.
+type ignoreRule struct {
+	base    string // directory holding the .gitignore
+	re      *regexp.Regexp
+	negate  bool // "!pattern" re-includes
+	dirOnly bool // "pattern/" only matches directories
+}
+
+// newGitignore loads the .gitignore files from the repository root containing
+// targetAbs down to targetAbs itself. Outside a repository only targetAbs's
+// own file is read. Files in subdirectories are added with load while walking.
+func newGitignore(fsys Filesystem, targetAbs string) (*gitignore, error) {
+	g := &gitignore{}
+	dirs := []string{targetAbs}
+	if top := GitRoot(targetAbs); top != "" {
+		for d := targetAbs; d != top; {
+			d = filepath.Dir(d)
+			dirs = append([]string{d}, dirs...)
+		}
+	}
+	for _, d := range dirs {
+		if err := g.load(fsys, d); err != nil { return nil, err }
+	}
+	return g, nil
+}
+
+// newRootIgnore loads the single ignore file name at targetAbs, such as
+// DefaultIgnoreFile. Unlike newGitignore nothing else is read, neither parents
+// nor subdirectories. It returns nil when the file has no rules.
+func newRootIgnore(fsys Filesystem, targetAbs, name string) (*gitignore, error) {
+	g := &gitignore{}
+	if err := g.loadFile(fsys, targetAbs, name); err != nil { return nil, err }
+	if len(g.rules) == 0 { return nil, nil }
+	return g, nil
+}
+
+// load adds the rules of dir/.gitignore, if there is one.
+func (g *gitignore) load(fsys Filesystem, dir string) error {
+	return g.loadFile(fsys, dir, ".gitignore")
+}
+
+// loadFile adds the rules of dir/name, if there is one.
+func (g *gitignore) loadFile(fsys Filesystem, dir, name string) error {
+	b, err := fsys.ReadFile(filepath.Join(dir, name))
+	if errors.Is(err, fs.ErrNotExist) { return nil }
+	if err != nil { return err }
+	for _, ln := range strings.Split(string(b), "\n") {
+		ln = strings.TrimRight(ln, "\r")
+		if !strings.HasSuffix(ln, `\ `) { ln = strings.TrimRight(ln, " ") }
+		if ln == "" || strings.HasPrefix(ln, "#") { continue }
+		r := ignoreRule{base: dir}
+		if strings.HasPrefix(ln, "!") {
+			r.negate, ln = true, ln[1:]
+		} else if strings.HasPrefix(ln, `\!`) || strings.HasPrefix(ln, `\#`) {
+			ln = ln[1:]
+		}
+		if strings.HasSuffix(ln, "/") {
+			r.dirOnly, ln = true, strings.TrimRight(ln, "/")
+		}
+		if ln == "" { continue }
+		// A slash anywhere but the end anchors the pattern to base.
+		anchored := strings.Contains(ln, "/")
+		ln = strings.TrimPrefix(ln, "/")
+		expr := ignoreGlobExpr(ln)
+		if !anchored { expr = "(?:.*/)?" + expr }
+		if r.re, err = regexp.Compile("^" + expr + "$"); err != nil { continue }
+		g.rules = append(g.rules, r)
+	}
+	return nil
+}
+
+// ignoreGlobExpr translates a gitignore glob into a regexp body: "*" and "?"
+// stay within one path segment, "**/" spans any number of directories and a
+// trailing "/**" matches everything inside.
+func ignoreGlobExpr(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case p[i:] == "**":
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(p[i+1:], ']')
+			if end < 0 { b.WriteString(`\[`); continue }
+			class := p[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") { class = "^" + class[1:] }
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(p):
+			i++
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// ignored reports whether path is ignored by the loaded rules.
+func (g *gitignore) ignored(path string, isDir bool) bool {
+	ignored := false
+	for _, r := range g.rules {
+		if r.dirOnly && !isDir { continue }
+		rel, err := filepath.Rel(r.base, path)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") { continue }
+		if r.re.MatchString(filepath.ToSlash(rel)) { ignored = !r.negate }
+	}
+	return ignored
+}
@@ -0,0 +1,175 @@
+package codedump
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// GoModCache returns the module cache directory reported by "go env GOMODCACHE",
+// or "" when the go tool is unavailable.
+func GoModCache() string {
+	out, err := exec.Command("go", "env", "GOMODCACHE").Output()
+	if err != nil { return "" }
+	return strings.TrimSpace(string(out))
+}
+
+// vendoredChecker reports whether a path belongs to third-party Go code: a
+// vendor/ directory below the target, or the module cache.
+type vendoredChecker struct {
+	targetAbs string
+	modCache  string
+}
+
+func newVendoredChecker(targetAbs string) vendoredChecker {
+	mc := GoModCache()
+	if mc != "" { mc = resolved(mc) }
+	return vendoredChecker{targetAbs: targetAbs, modCache: mc}
+}
+
+func (v vendoredChecker) isVendored(path string) bool {
+	if v.modCache != "" {
+		p := resolved(path)
+		if p == v.modCache || strings.HasPrefix(p, v.modCache+string(filepath.Separator)) { return true }
+	}
+	// Only directories below the target count, so a project checked out
+	// under a directory named vendor is not skipped as a whole.
+	rel, err := filepath.Rel(v.targetAbs, path)
+	if err != nil { return false }
+	for _, seg := range strings.Split(filepath.ToSlash(rel), "/") {
+		if seg == "vendor" { return true }
+	}
+	return false
+}
+
+// goModule describes the module a directory belongs to.
+type goModule struct {
+	Path string // module path from go.mod
+	Dir  string // directory containing go.mod
+}
+
+// moduleResolver finds the enclosing go.mod of directories, caching lookups.
+type moduleResolver struct {
+	cache map[string]*goModule
+}
+
+func newModuleResolver() *moduleResolver {
+	return &moduleResolver{cache: map[string]*goModule{}}
+}
+
+// module returns the module enclosing dir, or nil when there is none.
+func (m *moduleResolver) module(dir string) *goModule {
+	if mod, ok := m.cache[dir]; ok { return mod }
+	var mod *goModule
+	if data, err := os.ReadFile(filepath.Join(dir, "go.mod")); err == nil {
+		mod = &goModule{Path: modulePath(data), Dir: dir}
+	} else if parent := filepath.Dir(dir); parent != dir {
+		mod = m.module(parent)
+	}
+	m.cache[dir] = mod
+	return mod
+}
+
+// importPath returns the Go import path of the package in dir. Outside a module
+// it falls back to the slash-separated dir itself.
+func (m *moduleResolver) importPath(dir string) string {
+	mod := m.module(dir)
+	if mod == nil || mod.Path == "" { return filepath.ToSlash(dir) }
+	rel, err := filepath.Rel(mod.Dir, dir)
+	if err != nil || rel == "." { return mod.Path }
+	return mod.Path + "/" + filepath.ToSlash(rel)
+}
+
+// modulePath extracts the module path from go.mod contents.
+func modulePath(gomod []byte) string {
+	for _, ln := range strings.Split(string(gomod), "\n") {
+		f := strings.Fields(ln)
+		if len(f) >= 2 && f[0] == "module" { return strings.Trim(f[1], `"`) }
+	}
+	return ""
+}
+
+// goDirective extracts the version of the "go" directive from go.mod
+// contents, e.g. "1.22" or "1.24.5", or "" when there is none.
+func goDirective(gomod []byte) string {
+	for _, ln := range strings.Split(string(gomod), "\n") {
+		if f := strings.Fields(ln); len(f) >= 2 && f[0] == "go" { return f[1] }
+	}
+	return ""
+}
+
+// goLangVersion matches the language version, major.minor, at the start of a
+// go directive ("1.22.3") or a runtime.Version() ("go1.22.3").
+var goLangVersion = regexp.MustCompile(`^(?:go)?(\d+\.\d+)`)
+
+// GoVersionMatches reports whether the go directive of a go.mod, such as
+// "1.22" or "1.22.3", names the same language version, major.minor, as the Go
+// release runtime, such as runtime.Version(). Patch releases are ignored, and
+// development builds never match.
+func GoVersionMatches(directive, runtime string) bool {
+	d, r := goLangVersion.FindStringSubmatch(directive), goLangVersion.FindStringSubmatch(runtime)
+	return d != nil && r != nil && d[1] == r[1]
+}
+
+// ModuleDep is one requirement of a go.mod file.
+type ModuleDep struct {
+	Path     string
+	Version  string
+	Indirect bool // marked "// indirect"
+}
+
+// ModuleDeps returns the requirements listed in go.mod contents, from both
+// single-line and grouped "require" directives, in file order.
+func ModuleDeps(gomod []byte) []ModuleDep {
+	var out []ModuleDep
+	inBlock := false
+	for _, ln := range strings.Split(string(gomod), "\n") {
+		code, comment, _ := strings.Cut(ln, "//")
+		f := strings.Fields(code)
+		switch {
+		case inBlock && len(f) == 1 && f[0] == ")":
+			inBlock = false
+			continue
+		case !inBlock && len(f) >= 2 && f[0] == "require" && f[1] == "(":
+			inBlock = true
+			continue
+		case !inBlock && len(f) >= 1 && f[0] == "require":
+			f = f[1:]
+		case !inBlock:
+			continue
+		}
+		if len(f) < 2 { continue }
+		out = append(out, ModuleDep{
+			Path:     strings.Trim(f[0], `"`),
+			Version:  f[1],
+			Indirect: strings.TrimSpace(comment) == "indirect",
+		})
+	}
+	return out
+}
+
+// moduleDepsSection renders the body of the MODULE DEPS section for mod, whose
+// go.mod contents are gomod: the module path and go version, then the direct
+// and indirect dependencies, each with its version.
+func moduleDepsSection(mod *goModule, gomod []byte) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "module %s", mod.Path)
+	if v := goDirective(gomod); v != "" { fmt.Fprintf(&b, " (go %s)", v) }
+	deps := ModuleDeps(gomod)
+	for _, indirect := range []bool{false, true} {
+		var rows []ModuleDep
+		for _, d := range deps {
+			if d.Indirect == indirect { rows = append(rows, d) }
+		}
+		label := "direct"
+		if indirect { label = "indirect" }
+		fmt.Fprintf(&b, "\n\n%s (%d):", label, len(rows))
+		w := 0
+		for _, d := range rows { w = max(w, len(d.Path)) }
+		for _, d := range rows { fmt.Fprintf(&b, "\n  %-*s %s", w, d.Path, d.Version) }
+	}
+	return b.String()
+}
@@ -0,0 +1,62 @@
+package codedump
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PackageGraph returns a Graphviz DOT graph of the import dependencies between
+// the Go packages that own the given items. Imports of packages outside the
+// item set are left out, so the graph only shows the dumped code.
+func PackageGraph(items []Item) ([]byte, error) {
+	mods := newModuleResolver()
+	nodes := map[string]bool{}
+	imports := map[string]map[string]bool{}
+	fset := token.NewFileSet()
+	for _, it := range items {
+		if !strings.HasSuffix(it.abs, ".go") { continue }
+		data, err := it.read()
+		if err != nil { return nil, err }
+		f, err := parser.ParseFile(fset, it.abs, data, parser.ImportsOnly)
+		if err != nil { continue }
+		pkg := mods.importPath(filepath.Dir(it.abs))
+		nodes[pkg] = true
+		if imports[pkg] == nil { imports[pkg] = map[string]bool{} }
+		for _, imp := range f.Imports {
+			if p, err := strconv.Unquote(imp.Path.Value); err == nil { imports[pkg][p] = true }
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "digraph codedump {\n")
+	fmt.Fprintf(&buf, "\trankdir=LR;\n")
+	fmt.Fprintf(&buf, "\tnode [shape=box];\n")
+	for _, n := range sortedKeys(nodes) {
+		fmt.Fprintf(&buf, "\t%q [label=%q];\n", n, n)
+	}
+	for _, from := range sortedKeys(nodes) {
+		for _, to := range sortedKeys(imports[from]) {
+			if to != from && nodes[to] {
+				fmt.Fprintf(&buf, "\t%q -> %q;\n", from, to)
+			}
+		}
+	}
+	fmt.Fprintf(&buf, "}\n")
+	return buf.Bytes(), nil
+}
+
+// sortedKeys returns the keys of a string set in order.
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,48 @@
+package codedump
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+// compileGrep compiles Config.Grep, returning nil when it is empty.
+func compileGrep(c Config) (*regexp.Regexp, error) {
+	if c.Grep == "" { return nil, nil }
+	re, err := regexp.Compile(c.Grep)
+	if err != nil { return nil, fmt.Errorf("grep: %w", err) }
+	return re, nil
+}
+
+// Excerpt keeps only the lines of src matching re plus context lines around
+// each, joining separate windows with a gap line. It reports false, returning
+// src unchanged, when nothing matches.
+func Excerpt(src []byte, re *regexp.Regexp, context int, gap string) ([]byte, bool) {
+	lines := bytes.Split(bytes.TrimSuffix(src, []byte("\n")), []byte("\n"))
+	keep := make([]bool, len(lines))
+	hit := false
+	for i, ln := range lines {
+		if !re.Match(ln) { continue }
+		hit = true
+		for j := max(0, i-context); j <= min(len(lines)-1, i+context); j++ { keep[j] = true }
+	}
+	if !hit { return src, false }
+	var out bytes.Buffer
+	for i, ln := range lines {
+		if !keep[i] { continue }
+		if i > 0 && !keep[i-1] { fmt.Fprintf(&out, "%s\n", gap) }
+		out.Write(ln)
+		out.WriteByte('\n')
+	}
+	if !keep[len(lines)-1] { fmt.Fprintf(&out, "%s\n", gap) }
+	return out.Bytes(), true
+}
+
+// excerptGap is the line marking omitted lines in an excerpt of path.
+func excerptGap(c Config, path string) string {
+	if cs, ok := c.commentStyle(path); ok {
+		if len(cs.Line) > 0 { return cs.Line[0] + " ..." }
+		if cs.BlockStart != "" { return cs.BlockStart + " ... " + cs.BlockEnd }
+	}
+	return "..."
+}
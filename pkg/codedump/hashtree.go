@@ -0,0 +1,79 @@
+package codedump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// HashNode is one node of a Merkle tree over the dumped files. A file's hash is
+// its content sha256; a directory's hash is derived from its children's names,
+// kinds and hashes, so any change bubbles up to the root.
+type HashNode struct {
+	Name     string      `json:"name"`
+	Type     string      `json:"type"` // "dir" or "file"
+	Hash     string      `json:"hash"`
+	Children []*HashNode `json:"children,omitempty"`
+}
+
+// BuildHashTree builds the Merkle tree of items keyed by their #rel_path.
+func BuildHashTree(items []Item) *HashNode {
+	root := &HashNode{Name: ".", Type: "dir"}
+	for _, it := range items {
+		cur := root
+		parts := strings.Split(it.rel, "/")
+		for i, part := range parts {
+			if i == len(parts)-1 {
+				cur.Children = append(cur.Children, &HashNode{Name: part, Type: "file", Hash: it.sha})
+				break
+			}
+			var next *HashNode
+			for _, ch := range cur.Children {
+				if ch.Type == "dir" && ch.Name == part { next = ch; break }
+			}
+			if next == nil {
+				next = &HashNode{Name: part, Type: "dir"}
+				cur.Children = append(cur.Children, next)
+			}
+			cur = next
+		}
+	}
+	root.seal()
+	return root
+}
+
+// seal sorts children and computes directory hashes bottom-up.
+func (n *HashNode) seal() {
+	if n.Type != "dir" { return }
+	sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Name < n.Children[j].Name })
+	h := sha256.New()
+	for _, ch := range n.Children {
+		ch.seal()
+		fmt.Fprintf(h, "%s %s %s\n", ch.Type, ch.Hash, ch.Name)
+	}
+	n.Hash = hex.EncodeToString(h.Sum(nil))
+}
+
+// TreeHash fingerprints items as a whole: the sha256 of one
+// "<content sha256>  <rel_path>" line per file, sorted by path. It changes
+// with any file's path or content but not with mtimes or the order of items,
+// which makes it a stable cache key for a filtered source set.
+func TreeHash(items []Item) string {
+	sorted := append([]Item(nil), items...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].rel < sorted[j].rel })
+	h := sha256.New()
+	for _, it := range sorted {
+		fmt.Fprintf(h, "%s  %s\n", it.sha, it.rel)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeHashTree writes the Merkle tree of items as indented JSON.
+func writeHashTree(fsys Filesystem, path string, items []Item) error {
+	b, err := json.MarshalIndent(BuildHashTree(items), "", "  ")
+	if err != nil { return err }
+	return writeFile(fsys, path, append(b, '\n'))
+}
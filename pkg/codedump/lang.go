@@ -0,0 +1,344 @@
+package codedump
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"path/filepath"
+	"strings"
+)
+
+// CommentStyle describes a language's comment delimiters.
+type CommentStyle struct {
+	Line       []string // line comment prefixes, e.g. "//"
+	BlockStart string   // block comment opener, e.g. "/*" ("" if none)
+	BlockEnd   string   // block comment closer, e.g. "*/"
+	Quotes     string   // string literal delimiters; comment markers inside them are not comments
+	// SpacedLine makes a line comment start only at the start of a line or
+	// after whitespace, as "#" does in shell ($#, ${#a[@]}) and YAML (#fff).
+	SpacedLine bool
+	// TripleQuotes treats a tripled quote (""" or ''') as opening a string
+	// that runs, across lines, to the same three quotes, as in Python.
+	TripleQuotes bool
+}
+
+var (
+	cStyle     = CommentStyle{Line: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", Quotes: "\"'`"}
+	hashStyle  = CommentStyle{Line: []string{"#"}, Quotes: "\"'"}
+	shellStyle = CommentStyle{Line: []string{"#"}, Quotes: "\"'", SpacedLine: true}
+	pyStyle    = CommentStyle{Line: []string{"#"}, Quotes: "\"'", TripleQuotes: true}
+	dashStyle  = CommentStyle{Line: []string{"--"}, Quotes: "\"'"}
+	htmlStyle  = CommentStyle{BlockStart: "<!--", BlockEnd: "-->"}
+)
+
+// commentStyles maps a lower-case file extension to its comment syntax.
+var commentStyles = map[string]CommentStyle{
+	".go": cStyle, ".c": cStyle, ".h": cStyle, ".cc": cStyle, ".cpp": cStyle, ".hpp": cStyle,
+	".java": cStyle, ".kt": cStyle, ".scala": cStyle, ".cs": cStyle, ".swift": cStyle,
+	".rs": {Line: []string{"//"}, BlockStart: "/*", BlockEnd: "*/", Quotes: "\""},
+	".js": cStyle, ".jsx": cStyle, ".ts": cStyle, ".tsx": cStyle, ".mjs": cStyle, ".dart": cStyle,
+	".proto": cStyle, ".php": {Line: []string{"//", "#"}, BlockStart: "/*", BlockEnd: "*/", Quotes: "\"'"},
+	".css": {BlockStart: "/*", BlockEnd: "*/", Quotes: "\"'"}, ".scss": cStyle,
+	".py": pyStyle, ".rb": hashStyle, ".sh": shellStyle, ".bash": shellStyle, ".zsh": shellStyle,
+	".yaml": shellStyle, ".yml": shellStyle, ".toml": hashStyle, ".pl": hashStyle, ".r": hashStyle,
+	".sql": {Line: []string{"--"}, BlockStart: "/*", BlockEnd: "*/", Quotes: "\"'"}, ".lua": dashStyle, ".hs": dashStyle,
+	".html": htmlStyle, ".htm": htmlStyle, ".xml": htmlStyle, ".vue": htmlStyle, ".md": htmlStyle,
+}
+
+// commentStyleFor returns the comment syntax for path's extension.
+func commentStyleFor(path string) (CommentStyle, bool) {
+	cs, ok := commentStyles[strings.ToLower(filepath.Ext(path))]
+	return cs, ok
+}
+
+// commentStyle returns the comment syntax for path, preferring an entry in
+// c.CommentStyles over the built-in table.
+func (c Config) commentStyle(path string) (CommentStyle, bool) {
+	if cs, ok := c.CommentStyles[strings.ToLower(filepath.Ext(path))]; ok { return cs, true }
+	return commentStyleFor(path)
+}
+
+// ParseCommentStyle parses the RC form of a comment style: space-separated
+// line comment prefixes, then optionally "|" and a block opener and closer,
+// then optionally "|" and the string quote characters, e.g. "-- | /* */ | '\"".
+func ParseCommentStyle(v string) (CommentStyle, error) {
+	parts := strings.Split(v, "|")
+	if len(parts) > 3 { return CommentStyle{}, fmt.Errorf("comment style %q: too many \"|\" sections", v) }
+	cs := CommentStyle{Line: strings.Fields(parts[0])}
+	if len(parts) > 1 {
+		switch block := strings.Fields(parts[1]); len(block) {
+		case 0:
+		case 2: cs.BlockStart, cs.BlockEnd = block[0], block[1]
+		default: return CommentStyle{}, fmt.Errorf("comment style %q: block needs an opener and a closer", v)
+		}
+	}
+	if len(parts) > 2 { cs.Quotes = strings.Join(strings.Fields(parts[2]), "") }
+	return cs, nil
+}
+
+// lineCommentPrefix returns the line-comment prefix ln starts with, if any.
+func (cs CommentStyle) lineCommentPrefix(ln []byte) string {
+	t := bytes.TrimSpace(ln)
+	for _, p := range cs.Line {
+		if bytes.HasPrefix(t, []byte(p)) { return p }
+	}
+	return ""
+}
+
+// TrimLeadingComments shortens each comment block that precedes the first line
+// of code (license headers, file banners) to its first n lines followed by a
+// "..." marker. Comments after the first code line are left untouched. Files
+// whose language is unknown are returned unchanged.
+func TrimLeadingComments(path string, src []byte, n int) []byte {
+	cs, ok := commentStyleFor(path)
+	if !ok { return src }
+	return trimLeadingComments(cs, src, n)
+}
+
+func trimLeadingComments(cs CommentStyle, src []byte, n int) []byte {
+	if n <= 0 { return src }
+	lines := bytes.Split(src, []byte("\n"))
+	out := make([][]byte, 0, len(lines))
+	i := 0
+	if len(lines) > 0 && bytes.HasPrefix(lines[0], []byte("#!")) {
+		out = append(out, lines[0])
+		i = 1
+	}
+	for i < len(lines) {
+		ln := lines[i]
+		t := bytes.TrimSpace(ln)
+		if len(t) == 0 {
+			out = append(out, ln)
+			i++
+			continue
+		}
+		end := i
+		var marker []byte
+		if p := cs.lineCommentPrefix(ln); p != "" {
+			for end < len(lines) && cs.lineCommentPrefix(lines[end]) == p { end++ }
+			marker = []byte(p + " ...")
+			if end-i > n+1 {
+				out = append(out, lines[i:i+n]...)
+				out = append(out, marker)
+			} else {
+				out = append(out, lines[i:end]...)
+			}
+		} else if cs.BlockStart != "" && bytes.HasPrefix(t, []byte(cs.BlockStart)) {
+			for end < len(lines) {
+				rest := lines[end]
+				if end == i { rest = bytes.TrimSpace(rest)[len(cs.BlockStart):] }
+				end++
+				if bytes.Contains(rest, []byte(cs.BlockEnd)) { break }
+			}
+			if end-i > n+2 {
+				out = append(out, lines[i:i+n]...)
+				out = append(out, []byte("   ..."), lines[end-1])
+			} else {
+				out = append(out, lines[i:end]...)
+			}
+		} else {
+			break
+		}
+		i = end
+	}
+	out = append(out, lines[i:]...)
+	return bytes.Join(out, []byte("\n"))
+}
+
+// LeadingComment returns the text of the first comment block before the first
+// line of code, with comment delimiters removed. For Go files the package doc
+// comment is used instead.
+func LeadingComment(path string, src []byte) string {
+	if strings.HasSuffix(path, ".go") {
+		f, err := parser.ParseFile(token.NewFileSet(), path, src, parser.PackageClauseOnly|parser.ParseComments)
+		if err == nil && f.Doc != nil { return f.Doc.Text() }
+		return ""
+	}
+	cs, ok := commentStyleFor(path)
+	if !ok { return "" }
+	lines := bytes.Split(src, []byte("\n"))
+	i := 0
+	if len(lines) > 0 && bytes.HasPrefix(lines[0], []byte("#!")) { i = 1 }
+	for i < len(lines) && len(bytes.TrimSpace(lines[i])) == 0 { i++ }
+	if i == len(lines) { return "" }
+	var text []string
+	t := bytes.TrimSpace(lines[i])
+	if p := cs.lineCommentPrefix(lines[i]); p != "" {
+		for ; i < len(lines) && cs.lineCommentPrefix(lines[i]) == p; i++ {
+			text = append(text, string(bytes.TrimPrefix(bytes.TrimSpace(lines[i]), []byte(p))))
+		}
+	} else if cs.BlockStart != "" && bytes.HasPrefix(t, []byte(cs.BlockStart)) {
+		rest := string(t[len(cs.BlockStart):])
+		for {
+			if j := strings.Index(rest, cs.BlockEnd); j >= 0 {
+				text = append(text, rest[:j])
+				break
+			}
+			text = append(text, strings.TrimPrefix(strings.TrimSpace(rest), "*"))
+			if i++; i == len(lines) { break }
+			rest = string(bytes.TrimSpace(lines[i]))
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+// StripComments removes every comment from src according to cs. Comment
+// markers inside string literals are left alone, as are a leading "#!" line
+// and Go "//go:" directives. Lines left empty by the removal are dropped.
+func StripComments(cs CommentStyle, src []byte) []byte {
+	var out bytes.Buffer
+	var cut []bool // per output line: whether a comment was removed from it
+	lineCut := false
+	newline := func() { cut = append(cut, lineCut); lineCut = false }
+	i := 0
+	if bytes.HasPrefix(src, []byte("#!")) {
+		for i < len(src) && src[i] != '\n' { i++ }
+		out.Write(src[:i])
+	}
+	var quote byte
+	triple := false // quote is tripled
+	for i < len(src) {
+		ch := src[i]
+		rest := src[i:]
+		if quote != 0 {
+			out.WriteByte(ch)
+			i++
+			switch {
+			case ch == '\n':
+				newline()
+				if quote != '`' && !triple { quote = 0 } // an unterminated literal ends with its line
+			case ch == '\\' && quote != '`' && i < len(src) && src[i] != '\n':
+				out.WriteByte(src[i])
+				i++
+			case ch == quote && !triple:
+				quote = 0
+			case ch == quote && bytes.HasPrefix(src[i:], []byte{quote, quote}):
+				out.Write(src[i : i+2])
+				i += 2
+				quote = 0
+			}
+			continue
+		}
+		if ch == '\n' {
+			out.WriteByte(ch)
+			i++
+			newline()
+			continue
+		}
+		if strings.IndexByte(cs.Quotes, ch) >= 0 {
+			quote = ch
+			triple = cs.TripleQuotes && bytes.HasPrefix(rest, []byte{ch, ch, ch})
+			n := 1
+			if triple { n = 3 }
+			out.Write(rest[:n])
+			i += n
+			continue
+		}
+		if cs.BlockStart != "" && bytes.HasPrefix(rest, []byte(cs.BlockStart)) {
+			end := len(src)
+			if j := bytes.Index(rest[len(cs.BlockStart):], []byte(cs.BlockEnd)); j >= 0 {
+				end = i + len(cs.BlockStart) + j + len(cs.BlockEnd)
+			}
+			lineCut = true
+			for _, b := range src[i:end] {
+				if b != '\n' { continue }
+				out.WriteByte('\n')
+				newline()
+				lineCut = true
+			}
+			i = end
+			continue
+		}
+		spaced := i == 0 || src[i-1] == ' ' || src[i-1] == '\t' || src[i-1] == '\n'
+		if p := linePrefixAt(cs, rest); p != "" && (spaced || !cs.SpacedLine) && !bytes.HasPrefix(rest, []byte("//go:")) {
+			for i < len(src) && src[i] != '\n' { i++ }
+			lineCut = true
+			continue
+		}
+		out.WriteByte(ch)
+		i++
+	}
+	newline()
+	return dropCutLines(out.Bytes(), cut)
+}
+
+// dropCutLines trims the lines of src that had a comment removed, per cut,
+// and drops those left empty.
+func dropCutLines(src []byte, cut []bool) []byte {
+	lines := bytes.Split(src, []byte("\n"))
+	kept := lines[:0]
+	for n, ln := range lines {
+		if cut[n] {
+			ln = bytes.TrimRight(ln, " \t\r")
+			if len(bytes.TrimSpace(ln)) == 0 { continue }
+		}
+		kept = append(kept, ln)
+	}
+	return bytes.Join(kept, []byte("\n"))
+}
+
+// StripGoComments removes every comment from Go source, finding them with
+// go/scanner so that string and rune literals are never touched. "//go:" and
+// "//line" directives are kept, and lines left empty by the removal are
+// dropped. Source that does not scan cleanly is stripped as far as it goes.
+func StripGoComments(src []byte) []byte {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+	var out bytes.Buffer
+	var cut []bool // per output line: whether a comment was removed from it
+	lineCut, prev := false, 0
+	copyText := func(b []byte, isComment bool) {
+		for _, ch := range b {
+			if ch == '\n' {
+				out.WriteByte('\n')
+				cut = append(cut, lineCut)
+				lineCut = isComment
+			} else if !isComment {
+				out.WriteByte(ch)
+			}
+		}
+	}
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF { break }
+		if tok != token.COMMENT || strings.HasPrefix(lit, "//go:") || strings.HasPrefix(lit, "//line ") { continue }
+		start := file.Offset(pos)
+		end := len(src)
+		if lit[1] == '/' {
+			if i := bytes.IndexByte(src[start:], '\n'); i >= 0 { end = start + i }
+		} else if i := bytes.Index(src[start+2:], []byte("*/")); i >= 0 {
+			end = start + 2 + i + 2
+		}
+		copyText(src[prev:start], false)
+		lineCut = true
+		copyText(src[start:end], true)
+		prev = end
+	}
+	copyText(src[prev:], false)
+	cut = append(cut, lineCut)
+	return dropCutLines(out.Bytes(), cut)
+}
+
+// linePrefixAt returns the line comment prefix src starts with, if any.
+func linePrefixAt(cs CommentStyle, src []byte) string {
+	for _, p := range cs.Line {
+		if bytes.HasPrefix(src, []byte(p)) { return p }
+	}
+	return ""
+}
+
+// fileSummaryMax caps the length of the #summary: line written by Dump.
+const fileSummaryMax = 100
+
+// FileSummary returns the first sentence of path's leading comment, collapsed
+// to one line and cut to at most max runes ("" when there is none).
+func FileSummary(path string, src []byte, max int) string {
+	s := strings.Join(strings.Fields(LeadingComment(path, src)), " ")
+	if i := strings.Index(s, ". "); i >= 0 { s = s[:i+1] }
+	if r := []rune(s); max > 0 && len(r) > max { s = strings.TrimSpace(string(r[:max-1])) + "…" }
+	return s
+}
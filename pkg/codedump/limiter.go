@@ -0,0 +1,39 @@
+package codedump
+
+import "context"
+
+// DumpLimiter bounds how many dumps run at once, for services that embed the
+// package and must not exhaust file descriptors or memory under load. The zero
+// value is not usable; create one with NewDumpLimiter and share it.
+type DumpLimiter struct {
+	sem chan struct{}
+}
+
+// NewDumpLimiter returns a limiter allowing at most n concurrent dumps (n < 1
+// is treated as 1).
+func NewDumpLimiter(n int) *DumpLimiter {
+	if n < 1 { n = 1 }
+	return &DumpLimiter{sem: make(chan struct{}, n)}
+}
+
+// Acquire blocks until a slot is free or ctx is done. Every successful Acquire
+// must be paired with a Release.
+func (l *DumpLimiter) Acquire(ctx context.Context) error {
+	select {
+	case l.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (l *DumpLimiter) Release() { <-l.sem }
+
+// Dump runs Dump(c) once a slot is free. It returns ctx's error without
+// dumping if ctx ends first.
+func (l *DumpLimiter) Dump(ctx context.Context, c Config) (string, int, error) {
+	if err := l.Acquire(ctx); err != nil { return "", 0, err }
+	defer l.Release()
+	return Dump(c)
+}
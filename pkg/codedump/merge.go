@@ -0,0 +1,151 @@
+package codedump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values for MergeOptions.OnConflict.
+const (
+	ConflictError  = "error"  // stop with an error (default)
+	ConflictSuffix = "suffix" // keep both blocks, renaming the later one
+)
+
+// MergeOptions tunes MergeDumps.
+type MergeOptions struct {
+	OnConflict string // what to do when two dumps record one path with different hashes ("" = ConflictError)
+}
+
+// MergeResult describes what MergeDumps did.
+type MergeResult struct {
+	Files     int               // blocks written
+	Collapsed int               // blocks left out as identical to one already written
+	Renamed   map[string]string // new rel path -> recorded one, for blocks renamed on conflict
+}
+
+// MergeDumps combines the text dumps at paths into one text dump written to w,
+// without reading the files they record. Blocks keep their order, the first
+// dump's first. A block whose #rel_path was already written is left out when
+// its #sha256 matches and is a conflict otherwise: an error, or with
+// ConflictSuffix a block renamed to "name~2.ext" (the first free number) with
+// #renamed_from naming its recorded path. #duplicate_of and #similar_to
+// references follow such renames within their dump.
+//
+// The merged header lists the inputs in #merged_from and takes #pwd and #paths
+// from the first one, so Restore treats the result like its inputs; dumps
+// with different paths modes are not merged. Sections such as PACKAGES are
+// left out.
+func MergeDumps(w io.Writer, paths []string, opts MergeOptions) (MergeResult, error) {
+	res := MergeResult{Renamed: map[string]string{}}
+	switch opts.OnConflict {
+	case "", ConflictError, ConflictSuffix:
+	default: return res, fmt.Errorf("on-conflict: unknown mode %q (want error or suffix)", opts.OnConflict)
+	}
+	var header map[string]string
+	var files []File
+	written := map[string]string{} // rel path -> sha256
+	for _, p := range paths {
+		entries, h, err := readDump(p)
+		if err != nil { return res, fmt.Errorf("%s: %w", p, err) }
+		if header == nil {
+			header = h
+		} else if h["paths"] != header["paths"] {
+			return res, fmt.Errorf("%s: recorded with paths=%s, %s with paths=%s", p, h["paths"], paths[0], header["paths"])
+		}
+		renamed := map[string]string{} // recorded rel path -> new one, within this dump
+		for _, e := range entries {
+			rel, sha := e.RelPath(), e.SHA256()
+			if prev, ok := written[rel]; ok {
+				if prev == sha { res.Collapsed++; continue }
+				if opts.OnConflict != ConflictSuffix {
+					return res, fmt.Errorf("%s: %s conflicts with the block of an earlier dump (#sha256 %s, not %s)", p, rel, sha, prev)
+				}
+				renamed[rel] = suffixedName(rel, written)
+				rel = renamed[rel]
+				res.Renamed[rel] = e.RelPath()
+			}
+			written[rel] = sha
+			files = append(files, mergedFile(e, rel, renamed))
+		}
+	}
+
+	if header == nil { header = map[string]string{} }
+	inputs := make([]string, len(paths))
+	for i, p := range paths {
+		inputs[i] = filepath.ToSlash(p)
+	}
+	h := Header{Fields: []Field{
+		{"pwd", header["pwd"]},
+		{"generated_at", time.Now().Format(time.RFC3339)},
+		{"go_version", runtime.Version()},
+		{"merged_from", strings.Join(inputs, ", ")},
+		{"paths", header["paths"]},
+		{"sha256_of", header["sha256_of"]},
+		{"merge_collapsed", fmt.Sprint(res.Collapsed)},
+		{"merge_renamed", fmt.Sprint(len(res.Renamed))},
+	}}
+	f := textFormatter{}
+	if err := f.WriteHeader(w, h); err != nil { return res, err }
+	for _, file := range files {
+		if err := f.WriteFile(w, file); err != nil { return res, err }
+		res.Files++
+	}
+	return res, f.WriteFooter(w)
+}
+
+// readDump reads every block of the text dump at path, with its header.
+func readDump(path string) ([]*Entry, map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil { return nil, nil, err }
+	defer f.Close()
+
+	r := NewDumpReader(f)
+	var entries []*Entry
+	for {
+		e, err := r.Next()
+		if errors.Is(err, io.EOF) { break }
+		if err != nil { return nil, nil, err }
+		entries = append(entries, e)
+	}
+	return entries, r.Header, nil
+}
+
+// mergedFile turns a parsed block back into a File recorded as rel, keeping
+// its other header fields in order and pointing its references at the blocks'
+// new names in renamed.
+func mergedFile(e *Entry, rel string, renamed map[string]string) File {
+	size, _ := strconv.ParseInt(e.Meta["size_bytes"], 10, 64)
+	file := File{Rel: rel, Abs: e.Meta["abs_path"], Size: size, SHA256: e.SHA256(), Content: e.Content, NoContent: !e.HasContent()}
+	for _, k := range e.keys {
+		v := e.Meta[k]
+		switch k {
+		case "rel_path", "abs_path", "size_bytes", "sha256": continue
+		case "duplicate_of", "similar_to":
+			if n, ok := renamed[v]; ok { v = n }
+		}
+		file.Meta = append(file.Meta, Field{k, v})
+	}
+	if rel != e.RelPath() {
+		file.Meta = append(file.Meta, Field{"renamed_from", e.RelPath()})
+	}
+	return file
+}
+
+// suffixedName returns rel with "~N" inserted before its extension, for the
+// first N from 2 that taken does not hold yet.
+func suffixedName(rel string, taken map[string]string) string {
+	ext := path.Ext(rel)
+	stem := strings.TrimSuffix(rel, ext)
+	for n := 2; ; n++ {
+		name := fmt.Sprintf("%s~%d%s", stem, n, ext)
+		if _, ok := taken[name]; !ok { return name }
+	}
+}
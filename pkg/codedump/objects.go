@@ -0,0 +1,35 @@
+package codedump
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path/filepath"
+)
+
+// WriteObjectStore writes each item's raw content into a git-style content
+// addressed store under dir: objects/<sha[:2]>/<sha[2:]>, one blob per unique
+// hash, plus index.json mapping every #rel_path to its hash. Blobs already
+// present are left alone, so repeated runs only add new content.
+func WriteObjectStore(dir string, items []Item) error {
+	return writeObjectStore(OSFS{}, dir, items)
+}
+
+func writeObjectStore(fsys Filesystem, dir string, items []Item) error {
+	index := make(map[string]string, len(items))
+	for _, it := range items {
+		index[it.rel] = it.sha
+		obj := filepath.Join(dir, "objects", it.sha[:2], it.sha[2:])
+		if _, err := fsys.Stat(obj); err == nil {
+			continue
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		data, err := it.read()
+		if err != nil { return err }
+		if err := writeFile(fsys, obj, data); err != nil { return err }
+	}
+	b, err := json.MarshalIndent(index, "", "  ")
+	if err != nil { return err }
+	return writeFile(fsys, filepath.Join(dir, "index.json"), append(b, '\n'))
+}
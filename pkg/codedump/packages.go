@@ -0,0 +1,89 @@
+package codedump
+
+import (
+	"bytes"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PackageInfo is one Go package among the dumped files.
+type PackageInfo struct {
+	ImportPath string // with a "_test" suffix for external test packages
+	Files      int
+	Lines      int
+}
+
+// GoPackages groups the .go items by the package their package clause names
+// and returns one entry per package, sorted by import path. Files whose
+// package clause does not parse are left out.
+func GoPackages(items []Item) ([]PackageInfo, error) {
+	mods := newModuleResolver()
+	fset := token.NewFileSet()
+	var files []PackageInfo
+	for _, it := range items {
+		p, ok, err := goPackageOf(it, mods, fset)
+		if err != nil { return nil, err }
+		if ok { files = append(files, p) }
+	}
+	return mergePackages(files), nil
+}
+
+// goPackageOf returns the package entry of a single .go item, or false for
+// other files and files without a valid package clause.
+func goPackageOf(it Item, mods *moduleResolver, fset *token.FileSet) (PackageInfo, bool, error) {
+	if !strings.HasSuffix(it.abs, ".go") { return PackageInfo{}, false, nil }
+	data, err := it.read()
+	if err != nil { return PackageInfo{}, false, err }
+	f, err := parser.ParseFile(fset, it.abs, data, parser.PackageClauseOnly)
+	if err != nil { return PackageInfo{}, false, nil }
+	p := PackageInfo{ImportPath: mods.importPath(filepath.Dir(it.abs)), Files: 1, Lines: CountLines(data)}
+	if strings.HasSuffix(f.Name.Name, "_test") { p.ImportPath += "_test" }
+	return p, true, nil
+}
+
+// CountLines returns the number of lines in data, counting a final line
+// without a newline too, as the text format terminates it.
+func CountLines(data []byte) int {
+	n := bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' { n++ }
+	return n
+}
+
+// mergePackages sums the entries that share an import path, sorted by it.
+func mergePackages(files []PackageInfo) []PackageInfo {
+	index := map[string]int{}
+	var out []PackageInfo
+	for _, f := range files {
+		if i, ok := index[f.ImportPath]; ok {
+			out[i].Files += f.Files
+			out[i].Lines += f.Lines
+			continue
+		}
+		index[f.ImportPath] = len(out)
+		out = append(out, f)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ImportPath < out[j].ImportPath })
+	return out
+}
+
+// packagesTable renders pkgs as aligned "import path  files  LOC" rows with a
+// total at the end.
+func packagesTable(pkgs []PackageInfo) string {
+	w, files, lines := len("IMPORT PATH"), 0, 0
+	for _, p := range pkgs {
+		w = max(w, len(p.ImportPath))
+		files += p.Files
+		lines += p.Lines
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-*s %6s %8s\n", w, "IMPORT PATH", "FILES", "LOC")
+	for _, p := range pkgs {
+		fmt.Fprintf(&b, "%-*s %6d %8d\n", w, p.ImportPath, p.Files, p.Lines)
+	}
+	fmt.Fprintf(&b, "%-*s %6d %8d", w, fmt.Sprintf("(%d packages)", len(pkgs)), files, lines)
+	return b.String()
+}
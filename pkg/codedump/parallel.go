@@ -0,0 +1,38 @@
+package codedump
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// parallel calls fn for every index in [0, n) on up to workers goroutines and
+// returns the first error. Once a call fails no new ones start, though calls
+// already running finish.
+func parallel(n, workers int, fn func(i int) error) error {
+	if workers > n { workers = n }
+	if workers < 1 { workers = 1 }
+	var (
+		next   atomic.Int64
+		failed atomic.Bool
+		once   sync.Once
+		first  error
+		wg     sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !failed.Load() {
+				i := int(next.Add(1) - 1)
+				if i >= n { return }
+				if err := fn(i); err != nil {
+					once.Do(func() { first = err })
+					failed.Store(true)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return first
+}
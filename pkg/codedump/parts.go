@@ -0,0 +1,145 @@
+package codedump
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Part is one output file written by DumpParts.
+type Part struct {
+	Path  string // absolute path of the part
+	Files int    // number of file blocks in it
+	Bytes int64  // size of the part on disk
+}
+
+// partPath names part n (1-based) of out: "dump.txt" becomes "dump.part2.txt".
+func partPath(out string, n int) string {
+	ext := filepath.Ext(out)
+	return fmt.Sprintf("%s.part%d%s", strings.TrimSuffix(out, ext), n, ext)
+}
+
+// splitParts spreads items over n parts. By default the order is kept and each
+// part takes a contiguous run of about 1/n of the total size. With balance,
+// files are packed largest-first, each into the currently smallest part, and
+// every part is then put back in the original order; sizes end up closer at
+// the cost of related files landing in different parts. Parts that would be
+// empty are left out, so there are never more parts than items (but at least
+// one).
+func splitParts(items []Item, n int, balance bool) [][]Item {
+	n = max(min(n, len(items)), 1)
+	out := make([][]Item, n)
+	if balance {
+		idx := make([]int, len(items))
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.SliceStable(idx, func(a, b int) bool { return items[idx[a]].size > items[idx[b]].size })
+		sizes := make([]int64, n)
+		assigned := make([]int, len(items))
+		for _, i := range idx {
+			min := 0
+			for p := range sizes {
+				if sizes[p] < sizes[min] { min = p }
+			}
+			sizes[min] += items[i].size
+			assigned[i] = min
+		}
+		for i, it := range items {
+			out[assigned[i]] = append(out[assigned[i]], it)
+		}
+		return dropEmptyParts(out)
+	}
+	var total int64
+	for _, it := range items {
+		total += it.size
+	}
+	var acc int64
+	p := 0
+	for _, it := range items {
+		// Move on once the parts so far hold their share of the total.
+		if p < n-1 && len(out[p]) > 0 && acc >= total*int64(p+1)/int64(n) { p++ }
+		out[p] = append(out[p], it)
+		acc += it.size
+	}
+	return dropEmptyParts(out)
+}
+
+// dropEmptyParts returns parts without the empty ones, keeping one if all are.
+func dropEmptyParts(parts [][]Item) [][]Item {
+	kept := parts[:0]
+	for _, p := range parts {
+		if len(p) > 0 { kept = append(kept, p) }
+	}
+	if len(kept) == 0 { return parts[:1] }
+	return kept
+}
+
+// chunkPath names size-limited part n (1-based) of out: "dump.txt" becomes
+// "dump.002.txt".
+func chunkPath(out string, n int) string {
+	ext := filepath.Ext(out)
+	return fmt.Sprintf("%s.%03d%s", strings.TrimSuffix(out, ext), n, ext)
+}
+
+// chunkIndexPath is where the index of size-limited parts goes: "dump.txt"
+// gets "dump.index.json".
+func chunkIndexPath(out string) string {
+	return strings.TrimSuffix(out, filepath.Ext(out)) + ".index.json"
+}
+
+// splitBySize spreads items over as few parts as it takes to keep each within
+// limit bytes, in order and never splitting a file. size renders a part and
+// returns its length. Files are first placed by their individual size, then
+// each part is checked, and files that do not fit after all move on to the
+// next part. A file too big for a part of its own still gets one.
+func splitBySize(items []Item, limit int64, size func([]Item) (int64, error)) ([][]Item, error) {
+	base, err := size(nil)
+	if err != nil { return nil, err }
+	var out [][]Item
+	var cur []Item
+	curSize := base
+	for i := range items {
+		s, err := size(items[i : i+1])
+		if err != nil { return nil, err }
+		if len(cur) > 0 && curSize+s-base > limit {
+			out = append(out, cur)
+			cur, curSize = nil, base
+		}
+		cur = append(cur, items[i])
+		curSize += s - base
+	}
+	out = append(out, cur)
+	for pi := 0; pi < len(out); pi++ {
+		for len(out[pi]) > 1 {
+			s, err := size(out[pi])
+			if err != nil { return nil, err }
+			if s <= limit { break }
+			last := out[pi][len(out[pi])-1]
+			out[pi] = out[pi][:len(out[pi])-1]
+			if pi+1 == len(out) { out = append(out, nil) }
+			out[pi+1] = append([]Item{last}, out[pi+1]...)
+		}
+	}
+	return out, nil
+}
+
+// writePartIndex writes a JSON list of the parts, by file name, next to them.
+func writePartIndex(fsys Filesystem, path string, parts []Part) error {
+	type entry struct {
+		Name  string `json:"name"`
+		Files int    `json:"files"`
+		Bytes int64  `json:"bytes"`
+	}
+	idx := struct {
+		Parts []entry `json:"parts"`
+	}{}
+	for _, p := range parts {
+		idx.Parts = append(idx.Parts, entry{filepath.Base(p.Path), p.Files, p.Bytes})
+	}
+	b, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil { return err }
+	return writeFile(fsys, path, append(b, '\n'))
+}
@@ -0,0 +1,204 @@
+package codedump
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// sizedItems returns items named a, b, c, ... with the given sizes.
+func sizedItems(sizes ...int64) []Item {
+	items := make([]Item, len(sizes))
+	for i, s := range sizes {
+		items[i] = Item{rel: string(rune('a' + i)), size: s}
+	}
+	return items
+}
+
+// partNames returns the rel paths of each part, joined.
+func partNames(parts [][]Item) []string {
+	out := make([]string, len(parts))
+	for i, p := range parts {
+		for _, it := range p {
+			out[i] += it.rel
+		}
+	}
+	return out
+}
+
+func TestSplitBySize(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  int64
+		sizes []int64
+		limit int64
+		want  []string
+	}{
+		{"fits in one", 10, []int64{10, 20, 30}, 100, []string{"abc"}},
+		{"exact boundary", 10, []int64{40, 50, 40, 50}, 100, []string{"ab", "cd"}},
+		{"one byte over", 10, []int64{40, 51, 39}, 100, []string{"a", "bc"}},
+		{"oversized file gets its own part", 10, []int64{20, 500, 20, 20}, 100, []string{"a", "b", "cd"}},
+		{"oversized first and last", 10, []int64{500, 20, 500}, 100, []string{"a", "b", "c"}},
+		{"header over the limit", 150, []int64{1, 1, 1}, 100, []string{"a", "b", "c"}},
+		{"no files", 10, nil, 100, []string{""}},
+	}
+	for _, tt := range tests {
+		calls := 0
+		size := func(list []Item) (int64, error) {
+			calls++
+			n := tt.base
+			for _, it := range list {
+				n += it.size
+			}
+			return n, nil
+		}
+		got, err := splitBySize(sizedItems(tt.sizes...), tt.limit, size)
+		if err != nil { t.Fatal(err) }
+		if names := partNames(got); !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("%s: got parts %q, want %q", tt.name, names, tt.want)
+		}
+		if max := 1 + 2*len(tt.sizes); calls > max {
+			t.Errorf("%s: measured %d times, want at most %d", tt.name, calls, max)
+		}
+	}
+}
+
+// TestSplitBySizeRecheck covers a size that is not the sum of its files, as
+// with separators, so a part placed by file sizes can still be over.
+func TestSplitBySizeRecheck(t *testing.T) {
+	size := func(list []Item) (int64, error) {
+		n := int64(10)
+		for _, it := range list {
+			n += it.size
+		}
+		if len(list) > 1 { n += int64(len(list)) * 5 }
+		return n, nil
+	}
+	got, err := splitBySize(sizedItems(30, 30, 30), 100, size)
+	if err != nil { t.Fatal(err) }
+	if names := partNames(got); !reflect.DeepEqual(names, []string{"ab", "c"}) {
+		t.Errorf("got parts %q, want [ab c]", names)
+	}
+}
+
+// dumpPartsFixture writes files named f0.txt, f1.txt, ... with the given
+// sizes and returns a config dumping them to dir/dump.txt.
+func dumpPartsFixture(t *testing.T, sizes ...int) Config {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{}
+	for i, n := range sizes {
+		files[fmt.Sprintf("src/f%d.txt", i)] = strings.Repeat("x", n-1) + "\n"
+	}
+	writeFiles(t, dir, files)
+	c := DefaultConfig()
+	c.Root, c.Target, c.Out, c.Ext = dir, filepath.Join(dir, "src"), "dump.txt", ".txt"
+	c.Paths = PathsRelativeToOut
+	return c
+}
+
+// checkParts checks that parts hold want files each, that their recorded
+// sizes are their sizes on disk, and that those with several files fit limit.
+func checkParts(t *testing.T, parts []Part, limit int64, want []int) {
+	t.Helper()
+	var files []int
+	for _, p := range parts {
+		files = append(files, p.Files)
+		fi, err := os.Stat(p.Path)
+		if err != nil { t.Fatal(err) }
+		if fi.Size() != p.Bytes { t.Errorf("%s: recorded %d bytes, %d on disk", p.Path, p.Bytes, fi.Size()) }
+		if p.Files > 1 && p.Bytes > limit { t.Errorf("%s: %d bytes for %d files, over %d", p.Path, p.Bytes, p.Files, limit) }
+	}
+	if !reflect.DeepEqual(files, want) { t.Errorf("files per part = %v, want %v", files, want) }
+}
+
+func TestDumpPartsMaxOutputBytes(t *testing.T) {
+	t.Run("oversized file", func(t *testing.T) {
+		c := dumpPartsFixture(t, 100, 100, 20000, 100, 100)
+		c.MaxOutputBytes = 4000
+		var warnings []string
+		c.Warnf = func(format string, args ...any) { warnings = append(warnings, fmt.Sprintf(format, args...)) }
+		parts, n, err := DumpParts(c)
+		if err != nil { t.Fatal(err) }
+		if n != 5 { t.Fatalf("dumped %d files, want 5", n) }
+		checkParts(t, parts, c.MaxOutputBytes, []int{2, 1, 2})
+		if len(warnings) != 1 || !strings.Contains(warnings[0], "f2.txt alone does not fit") {
+			t.Errorf("warnings = %q, want one about f2.txt", warnings)
+		}
+	})
+
+	t.Run("exact boundary", func(t *testing.T) {
+		// A part exactly at the limit is kept whole; a byte less moves its
+		// last file on. Both parts have the same size here.
+		c := dumpPartsFixture(t, 300, 300, 300, 300)
+		c.MaxOutputBytes = 2000
+		parts, _, err := DumpParts(c)
+		if err != nil { t.Fatal(err) }
+		checkParts(t, parts, c.MaxOutputBytes, []int{2, 2})
+
+		c.MaxOutputBytes = parts[0].Bytes
+		parts, _, err = DumpParts(c)
+		if err != nil { t.Fatal(err) }
+		checkParts(t, parts, c.MaxOutputBytes, []int{2, 2})
+		if parts[0].Bytes != c.MaxOutputBytes { t.Errorf("part 1 is %d bytes, want exactly %d", parts[0].Bytes, c.MaxOutputBytes) }
+
+		c.MaxOutputBytes--
+		parts, _, err = DumpParts(c)
+		if err != nil { t.Fatal(err) }
+		checkParts(t, parts, c.MaxOutputBytes, []int{1, 1, 1, 1})
+	})
+
+	t.Run("header over the limit", func(t *testing.T) {
+		c := dumpPartsFixture(t, 10, 10, 10)
+		c.Tree = true
+		c.MaxOutputBytes = 50
+		var warnings []string
+		c.Warnf = func(format string, args ...any) { warnings = append(warnings, fmt.Sprintf(format, args...)) }
+		parts, n, err := DumpParts(c)
+		if err != nil { t.Fatal(err) }
+		if n != 3 { t.Fatalf("dumped %d files, want 3", n) }
+		checkParts(t, parts, c.MaxOutputBytes, []int{1, 1, 1})
+		if len(warnings) != 3 { t.Errorf("got %d warnings, want one per part: %q", len(warnings), warnings) }
+		if _, err := os.Stat(filepath.Join(c.Root, "dump.index.json")); err != nil { t.Error(err) }
+	})
+}
+
+func TestSplitPartsNeverEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		sizes   []int64
+		n       int
+		balance bool
+		want    []string
+	}{
+		{"fewer items than parts", []int64{10, 10}, 5, false, []string{"a", "b"}},
+		{"fewer items than parts, balanced", []int64{10, 10}, 5, true, []string{"a", "b"}},
+		{"large last file", []int64{1, 1, 100}, 3, false, []string{"abc"}},
+		{"empty files, balanced", []int64{0, 0, 0, 10}, 3, true, []string{"d", "abc"}},
+		{"no items", nil, 3, false, []string{""}},
+		{"even split", []int64{10, 10, 10, 10}, 2, false, []string{"ab", "cd"}},
+	}
+	for _, tt := range tests {
+		got := splitParts(sizedItems(tt.sizes...), tt.n, tt.balance)
+		if names := partNames(got); !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("%s: got parts %q, want %q", tt.name, names, tt.want)
+		}
+	}
+}
+
+func TestDumpPartsFewerFilesThanParts(t *testing.T) {
+	c := dumpPartsFixture(t, 100, 100)
+	c.Parts = 4
+	parts, _, err := DumpParts(c)
+	if err != nil { t.Fatal(err) }
+	checkParts(t, parts, 1<<20, []int{1, 1})
+	for k := 3; k <= 4; k++ {
+		if _, err := os.Stat(partPath(filepath.Join(c.Root, c.Out), k)); !os.IsNotExist(err) { t.Errorf("part %d was written", k) }
+	}
+	b, err := os.ReadFile(parts[1].Path)
+	if err != nil { t.Fatal(err) }
+	if !strings.Contains(string(b), "// #part: 2/2\n") { t.Errorf("%s does not say it is part 2/2", parts[1].Path) }
+}
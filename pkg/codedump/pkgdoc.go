@@ -0,0 +1,31 @@
+package codedump
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PackageDoc returns the package documentation of the Go package in dir, taken
+// from the package comments of its non-test files (typically doc.go).
+func PackageDoc(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil { return "", err }
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") { continue }
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.PackageClauseOnly|parser.ParseComments)
+		if err != nil { continue }
+		files = append(files, f)
+	}
+	if len(files) == 0 { return "", nil }
+	p, err := doc.NewFromFiles(fset, files, "")
+	if err != nil { return "", err }
+	return strings.TrimSpace(p.Doc), nil
+}
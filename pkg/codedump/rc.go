@@ -0,0 +1,294 @@
+package codedump
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WriteDefaultRC writes a new RC file with defaults to the given path.
+func WriteDefaultRC(path string) error {
+	content := `# .codedumprc
+# Root of the project (where the final TXT will be saved)
+root=.
+
+# Target folder to scan (comma separated for several)
+target=./models
+
+# Output file name (relative to root)
+out=models_tree.txt
+
+# File extensions to include (comma separated)
+ext=.go
+
+# Substrings to exclude (comma separated, relative to target;
+# a leading / anchors to the target root, **/ matches at any depth)
+exclude=_test.go,**/.git/,**/vendor/
+
+# Required substrings, any of which the path must contain (comma separated, optional)
+include=
+
+# Keep "package" line (true/false)
+pkg=false
+
+# Profiles: keys under a [name] section override the ones above when
+# running with -profile name.
+# [ci]
+# out=ci_tree.txt
+`
+	return os.WriteFile(path, []byte(content), 0o644)
+}
+
+// ReadRC populates the given Config from a RC file, ignoring profile sections.
+func ReadRC(path string, c *Config) error {
+	return ReadRCProfile(path, "", c)
+}
+
+// ReadRCProfile populates the given Config from a RC file. Keys before the first
+// "[name]" section header form the base config; when profile is non-empty, the
+// keys of the matching section are applied on top of it. Unknown keys and
+// invalid values are reported through c.Warnf and otherwise skipped, so a typo
+// never stops a run but does not go unnoticed either. A YAML config (see
+// IsYAMLConfig) is read with ReadYAMLConfig and has no profiles.
+func ReadRCProfile(path, profile string, c *Config) error {
+	if IsYAMLConfig(path) {
+		if profile != "" { return fmt.Errorf("profile [%s]: %s is a YAML config, profiles need a %s", profile, path, DefaultRCName) }
+		return ReadYAMLConfig(path, c)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil { return err }
+	found := false
+	for _, ln := range parseRCLines(b) {
+		if ln.header {
+			if ln.section == profile { found = true }
+			continue
+		}
+		if ln.malformed || (ln.section != "" && ln.section != profile) { continue }
+		if err := setRCKey(c, ln.key, ln.value); err != nil {
+			c.warnf("%s:%d: %v", path, ln.n, err)
+		}
+	}
+	if profile != "" && !found {
+		return fmt.Errorf("profile [%s] not found", profile)
+	}
+	return nil
+}
+
+// rcLine is one meaningful line of an RC file.
+type rcLine struct {
+	n          int    // 1-based line number
+	section    string // enclosing [name] section ("" = base config)
+	header     bool   // the line is a section header
+	malformed  bool   // neither a header nor key=value
+	key, value string
+}
+
+// parseRCLines splits RC content into lines, skipping blanks and comments.
+func parseRCLines(b []byte) []rcLine {
+	var out []rcLine
+	section := ""
+	for i, ln := range strings.Split(string(b), "\n") {
+		ln = strings.TrimSpace(ln)
+		if ln == "" || strings.HasPrefix(ln, "#") { continue }
+		l := rcLine{n: i + 1, section: section}
+		if strings.HasPrefix(ln, "[") && strings.HasSuffix(ln, "]") {
+			section = strings.TrimSpace(ln[1 : len(ln)-1])
+			l.section, l.header = section, true
+		} else if k, v, ok := strings.Cut(ln, "="); ok && strings.TrimSpace(k) != "" {
+			l.key, l.value = strings.TrimSpace(k), strings.TrimSpace(v)
+		} else {
+			l.malformed = true
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+// RCProblem is one issue found by ValidateRC.
+type RCProblem struct {
+	Line int
+	Msg  string
+}
+
+// ValidateRC checks an RC file without applying it, reporting malformed lines,
+// unknown keys (such as a misspelled "exlude") and values of the wrong type.
+// YAML configs are checked too.
+func ValidateRC(path string) ([]RCProblem, error) {
+	b, err := os.ReadFile(path)
+	if err != nil { return nil, err }
+	lines, malformed := parseRCLines(b), "malformed line, want key=value or [section]"
+	if IsYAMLConfig(path) { lines, malformed = parseYAMLLines(b), "malformed line, want key: value or a list item" }
+	var probs []RCProblem
+	for _, ln := range lines {
+		switch {
+		case ln.header && ln.section == "":
+			probs = append(probs, RCProblem{ln.n, "empty section name"})
+		case ln.header:
+		case ln.malformed:
+			probs = append(probs, RCProblem{ln.n, malformed})
+		default:
+			var scratch Config
+			if err := setRCKey(&scratch, ln.key, ln.value); err != nil {
+				probs = append(probs, RCProblem{ln.n, err.Error()})
+			}
+		}
+	}
+	return probs, nil
+}
+
+// errUnknownRCKey is returned by setRCKey for keys it does not know.
+var errUnknownRCKey = errors.New("unknown key")
+
+// setRCKey applies one RC key/value pair to c. A value of the wrong type still
+// sets the field's zero value, and the error says why.
+func setRCKey(c *Config, k, v string) error {
+	if ext, ok := strings.CutPrefix(strings.ToLower(k), "comment."); ok {
+		cs, err := ParseCommentStyle(v)
+		if err != nil { return fmt.Errorf("%s: %w", k, err) }
+		if c.CommentStyles == nil { c.CommentStyles = map[string]CommentStyle{} }
+		c.CommentStyles["."+strings.TrimPrefix(ext, ".")] = cs
+		return nil
+	}
+	if ext, ok := strings.CutPrefix(strings.ToLower(k), "strip_mode."); ok {
+		if err := checkStripMode(v); err != nil { return fmt.Errorf("%s: %w", k, err) }
+		if c.StripModes == nil { c.StripModes = map[string]string{} }
+		c.StripModes["."+strings.TrimPrefix(ext, ".")] = v
+		return nil
+	}
+	var err error
+	switch strings.ToLower(k) {
+	case "root": c.Root = v
+	case "target": c.Target = v
+	case "out": c.Out = v
+	case "ext": c.Ext = v
+	case "exclude": c.Exclude = v
+	case "include": c.Include = v
+	case "pkg": c.Pkg, err = parseBool(v)
+	case "format": c.Format = v
+	case "strip_exts": c.StripExts = v
+	case "branch_diff": c.BranchDiff, err = parseBool(v)
+	case "base_branch": c.BaseBranch = v
+	case "skip_vendored": c.SkipVendored, err = parseBool(v)
+	case "summary": c.Summary = v
+	case "trim_comments_to": c.TrimCommentsTo, err = strconv.Atoi(v)
+	case "paths": c.Paths = v
+	case "graph": c.Graph = v
+	case "expect": c.Expect = v
+	case "package_docs": c.PackageDocs, err = parseBool(v)
+	case "hash_tree": c.HashTree = v
+	case "follow_embeds": c.FollowEmbeds, err = parseBool(v)
+	case "funcs": c.Funcs = v
+	case "uses": c.Uses = v
+	case "checkpoint": c.Checkpoint = v
+	case "shuffle": c.Shuffle, err = parseBool(v)
+	case "seed": c.Seed, err = strconv.ParseInt(v, 10, 64)
+	case "sort": c.Sort = v
+	case "sort_desc": c.SortDesc, err = parseBool(v)
+	case "size_group": c.SizeGroup, err = parseBool(v)
+	case "size_group_small": c.SizeGroupSmall, err = ParseSize(v)
+	case "size_group_large": c.SizeGroupLarge, err = ParseSize(v)
+	case "dupe_report": c.DupeReport = v
+	case "dupe_window": c.DupeWindow, err = strconv.Atoi(v)
+	case "todo_index": c.TodoIndex = v
+	case "todo_keywords": c.TodoKeywords = v
+	case "collapse_blanks": c.CollapseBlanks, err = parseBool(v)
+	case "skip_build_ignore": c.SkipBuildIgnore, err = parseBool(v)
+	case "file_summary": c.FileSummary, err = parseBool(v)
+	case "prune_glob": c.PruneGlob = v
+	case "with_testdata": c.WithTestdata, err = parseBool(v)
+	case "max_depth": c.MaxDepth, err = strconv.Atoi(v)
+	case "object_store": c.ObjectStore = v
+	case "warn_line_length": c.WarnLineLength, err = strconv.Atoi(v)
+	case "skip_long_lines": c.SkipLongLines, err = parseBool(v)
+	case "strip": c.Strip = v
+	case "relativize_paths": c.RelativizePaths, err = parseBool(v)
+	case "group_by": c.GroupBy = v
+	case "one_per_dir": c.OnePerDir, err = parseBool(v)
+	case "coverprofile": c.CoverProfile = v
+	case "annotate_coverage": c.AnnotateCoverage, err = parseBool(v)
+	case "chat_delimiter": c.ChatDelimiter = v
+	case "chat_preamble": c.ChatPreamble = v
+	case "skip_symlinks": c.SkipSymlinks, err = parseBool(v)
+	case "follow_symlinks": c.FollowSymlinks, err = parseBool(v)
+	case "older_than": c.OlderThan = v
+	case "newer_than": c.NewerThan = v
+	case "git_age": c.GitAge, err = parseBool(v)
+	case "relative_age": c.RelativeAge, err = parseBool(v)
+	case "grep": c.Grep = v
+	case "grep_context": c.GrepContext, err = strconv.Atoi(v)
+	case "gitignore": c.GitIgnore, err = parseBool(v)
+	case "ignore_file": c.IgnoreFile = v
+	case "concurrency": c.Concurrency, err = strconv.Atoi(v)
+	case "fail_fast": c.FailFast, err = parseBool(v)
+	case "models_summary": c.ModelsSummary, err = parseBool(v)
+	case "docs_only": c.DocsOnly, err = parseBool(v)
+	case "docs_skip_other": c.DocsSkipOther, err = parseBool(v)
+	case "timestamp_out": c.TimestampOut, err = parseBool(v)
+	case "timestamp_layout": c.TimestampLayout = v
+	case "replace": c.Replace = v
+	case "package_readmes": c.PackageReadmes, err = parseBool(v)
+	case "tree": c.Tree, err = parseBool(v)
+	case "packages_overview": c.PackagesOverview, err = parseBool(v)
+	case "mod_graph": c.ModGraph, err = parseBool(v)
+	case "check_go_version": c.CheckGoVersion, err = parseBool(v)
+	case "strict_go_version": c.StrictGoVersion, err = parseBool(v)
+	case "max_tokens": c.MaxTokens, err = strconv.Atoi(v)
+	case "chunk_hashes": c.ChunkHashes, err = strconv.Atoi(v)
+	case "compression_stats": c.CompressionStats, err = parseBool(v)
+	case "max_dir_files": c.MaxDirFiles, err = strconv.Atoi(v)
+	case "max_file_size": c.MaxFileSize, err = ParseSize(v)
+	case "skip_empty": c.SkipEmpty, err = parseBool(v)
+	case "files": c.Files = v
+	case "filters": c.Filters = v
+	case "binary_preview": c.BinaryPreview, err = strconv.Atoi(v)
+	case "strip_comments": c.StripComments, err = parseBool(v)
+	case "require_utf8": c.RequireUTF8, err = parseBool(v)
+	case "on_invalid": c.OnInvalid = v
+	case "blame_files": c.BlameFiles = v
+	case "parts": c.Parts, err = strconv.Atoi(v)
+	case "max_output_bytes": c.MaxOutputBytes, err = ParseSize(v)
+	case "balance_parts": c.BalanceParts, err = parseBool(v)
+	case "similarity_dedupe": c.SimilarityDedupe, err = strconv.ParseFloat(v, 64)
+	case "dedup": c.Dedup, err = parseBool(v)
+	default: return fmt.Errorf("%q: %w", k, errUnknownRCKey)
+	}
+	if err != nil { return fmt.Errorf("%s: invalid value %q", k, v) }
+	return nil
+}
+
+// parseBool accepts the RC spellings of true ("true", "1", "yes") and false
+// ("false", "0", "no", or empty).
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "true", "1", "yes": return true, nil
+	case "false", "0", "no", "": return false, nil
+	}
+	return false, fmt.Errorf("not a boolean: %q", v)
+}
+
+// FindRC searches for a .codedumprc, or else a .codedump.yaml, starting from
+// the CWD up to root, then $HOME.
+func FindRC() string {
+	wd, _ := os.Getwd()
+	cur := wd
+	for {
+		if rc := rcIn(cur); rc != "" { return rc }
+		parent := filepath.Dir(cur)
+		if parent == cur { break }
+		cur = parent
+	}
+	if home, err := os.UserHomeDir(); err == nil { return rcIn(home) }
+	return ""
+}
+
+// rcIn returns the config file in dir, preferring the RC format, or "".
+func rcIn(dir string) string {
+	for _, name := range []string{DefaultRCName, DefaultYAMLName} {
+		rc := filepath.Join(dir, name)
+		if _, err := os.Stat(rc); err == nil { return rc }
+	}
+	return ""
+}
@@ -0,0 +1,190 @@
+package codedump
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Markers framing the text dump format.
+const (
+	markHeaderBegin = "// ===== CODEDUMP GENERATED ====="
+	markHeaderEnd   = "// ================================="
+	markFileBegin   = "// ===== BEGIN FILE ====="
+	markMetaEnd     = "// ======================"
+	markFileEnd     = "// ===== END FILE ====="
+)
+
+// markPrefix starts every framing line of the text format.
+const markPrefix = "// ====="
+
+// EscapeContent makes file content safe to embed between framing markers. Any
+// line that, after zero or more leading backslashes, starts with "// =====" gets
+// one extra leading backslash, so content can never be mistaken for a marker.
+// UnescapeContent reverses it.
+func EscapeContent(src []byte) []byte {
+	if !bytes.Contains(src, []byte(markPrefix)) { return src }
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	var out bytes.Buffer
+	for _, ln := range lines {
+		if isMarkerLike(string(ln)) { out.WriteByte('\\') }
+		out.Write(ln)
+	}
+	return out.Bytes()
+}
+
+// UnescapeContent reverses EscapeContent.
+func UnescapeContent(src []byte) []byte {
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	var out bytes.Buffer
+	for _, ln := range lines {
+		out.WriteString(unescapeLine(string(ln)))
+	}
+	return out.Bytes()
+}
+
+// isMarkerLike reports whether ln is "// =====..." behind any number of backslashes.
+func isMarkerLike(ln string) bool {
+	return strings.HasPrefix(strings.TrimLeft(ln, "\\"), markPrefix)
+}
+
+// unescapeLine drops the escape backslash EscapeContent added to ln, if any.
+func unescapeLine(ln string) string {
+	if strings.HasPrefix(ln, "\\") && isMarkerLike(ln) { return ln[1:] }
+	return ln
+}
+
+// Entry is one file block parsed back from a text dump.
+type Entry struct {
+	Meta    map[string]string // header fields keyed without the '#', e.g. "rel_path"
+	Content []byte            // file content, unescaped
+	keys    []string          // Meta keys in the order the block lists them
+}
+
+// RelPath returns the block's recorded #rel_path.
+func (e *Entry) RelPath() string { return e.Meta["rel_path"] }
+
+// SHA256 returns the block's recorded #sha256.
+func (e *Entry) SHA256() string { return e.Meta["sha256"] }
+
+// HasContent reports whether the block carries file content, as opposed to a
+// reference such as #similar_to or #duplicate_of.
+func (e *Entry) HasContent() bool { return e.Meta["similar_to"] == "" && e.Meta["duplicate_of"] == "" }
+
+// Transformed reports whether the block's content was deliberately altered
+// from the file (package line stripped, comments trimmed, ...), so it is not
+// expected to match #sha256.
+func (e *Entry) Transformed() bool { return e.Meta["transformed"] == "true" }
+
+// Verify checks the block's content against its recorded #sha256. It returns
+// the content that matched, which drops the newline the text format adds to
+// files that lack a final one, or Content unchanged and false on a mismatch.
+func (e *Entry) Verify() ([]byte, bool) {
+	return matchSHA256(e.Content, e.SHA256())
+}
+
+// matchSHA256 checks content, or content without the newline the text format
+// adds, against the hex sha256 want, returning the variant that matched.
+func matchSHA256(content []byte, want string) ([]byte, bool) {
+	if sha256Hex(content) == want { return content, true }
+	if trimmed, ok := bytes.CutSuffix(content, []byte("\n")); ok && sha256Hex(trimmed) == want { return trimmed, true }
+	return content, false
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// DumpReader parses a text dump produced by Dump, one file block at a time.
+type DumpReader struct {
+	br     *bufio.Reader
+	line   int
+	Header map[string]string // generation header fields, filled by the first Next call
+	inited bool
+}
+
+// NewDumpReader returns a reader over a text dump.
+func NewDumpReader(r io.Reader) *DumpReader {
+	return &DumpReader{br: bufio.NewReader(r), Header: map[string]string{}}
+}
+
+// readLine returns the next line including its terminator; io.EOF once exhausted.
+func (r *DumpReader) readLine() (string, error) {
+	s, err := r.br.ReadString('\n')
+	if err == io.EOF && s != "" { err = nil }
+	if err == nil { r.line++ }
+	return s, err
+}
+
+func (r *DumpReader) errorf(format string, args ...any) error {
+	return fmt.Errorf("dump line %d: %s", r.line, fmt.Sprintf(format, args...))
+}
+
+// parseMeta splits a "// #key: value" line.
+func parseMeta(ln string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(ln, "// #")
+	if !ok { return "", "", false }
+	k, v, ok := strings.Cut(rest, ":")
+	if !ok { return "", "", false }
+	return strings.TrimSpace(k), strings.TrimSpace(v), true
+}
+
+// Next returns the next file block, or io.EOF after the last one.
+func (r *DumpReader) Next() (*Entry, error) {
+	if !r.inited {
+		r.inited = true
+		if err := r.readHeader(); err != nil { return nil, err }
+	}
+	for {
+		ln, err := r.readLine()
+		if err != nil { return nil, err }
+		t := strings.TrimRight(ln, "\r\n")
+		if t == markFileBegin { break }
+		// Blank lines and comment sections between blocks (package intros and
+		// the like) carry no file content.
+		if t == "" || strings.HasPrefix(t, "//") { continue }
+		return nil, r.errorf("expected %q, got %q", markFileBegin, t)
+	}
+	e := &Entry{Meta: map[string]string{}}
+	for {
+		ln, err := r.readLine()
+		if err != nil { return nil, r.errorf("unterminated file header: %v", err) }
+		t := strings.TrimRight(ln, "\r\n")
+		if t == markMetaEnd { break }
+		k, v, ok := parseMeta(t)
+		if !ok { return nil, r.errorf("malformed file header line %q", t) }
+		if _, dup := e.Meta[k]; !dup { e.keys = append(e.keys, k) }
+		e.Meta[k] = v
+	}
+	var content strings.Builder
+	for {
+		ln, err := r.readLine()
+		if err == io.EOF { return nil, r.errorf("missing %q for %s", markFileEnd, e.RelPath()) }
+		if err != nil { return nil, err }
+		if strings.TrimRight(ln, "\r\n") == markFileEnd { break }
+		content.WriteString(unescapeLine(ln))
+	}
+	if e.HasContent() { e.Content = []byte(content.String()) }
+	return e, nil
+}
+
+// readHeader consumes the generation header at the top of the dump.
+func (r *DumpReader) readHeader() error {
+	ln, err := r.readLine()
+	if err == io.EOF { return errors.New("empty dump") }
+	if err != nil { return err }
+	if strings.TrimRight(ln, "\r\n") != markHeaderBegin { return r.errorf("not a codedump text dump") }
+	for {
+		ln, err := r.readLine()
+		if err != nil { return r.errorf("unterminated dump header: %v", err) }
+		t := strings.TrimRight(ln, "\r\n")
+		if t == markHeaderEnd { return nil }
+		if k, v, ok := parseMeta(t); ok { r.Header[k] = v }
+	}
+}
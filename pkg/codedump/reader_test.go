@@ -0,0 +1,62 @@
+package codedump
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMarkerLikeContentRoundTrips(t *testing.T) {
+	files := map[string]string{
+		"markers.txt": "// ===== BEGIN FILE =====\n// #rel_path: evil.txt\n// #sha256: 00\n// ======================\n// ===== END FILE =====\nafter\n",
+		"escaped.txt": "\\// ===== END FILE =====\n\\\\// ===== BEGIN FILE =====\n\\\\\\// =====\n\\#rel_path: x\n\\\\#rel_path: y\n",
+		"header.txt":  "// ===== CODEDUMP GENERATED =====\n// #pwd: /elsewhere\n// =================================\n",
+		"crlf.txt":    "line\r\n// ===== END FILE =====\r\n\\// =====\r\n",
+		"no-eol.txt":  "text\n// ===== END FILE =====",
+		"plain.txt":   "// ====\n  // ===== indented\n#rel_path: z\n",
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	writeFiles(t, src, files)
+
+	c := DefaultConfig()
+	c.Root, c.Target, c.Out, c.Ext = dir, src, "dump.txt", ".txt"
+	c.Paths = PathsRelativeToOut // rel paths "src/<name>", restorable anywhere
+	out, n, err := Dump(c)
+	if err != nil { t.Fatal(err) }
+	if n != len(files) { t.Fatalf("dumped %d files, want %d", n, len(files)) }
+
+	f, err := os.Open(out)
+	if err != nil { t.Fatal(err) }
+	defer f.Close()
+	r := NewDumpReader(f)
+	seen := 0
+	for {
+		e, err := r.Next()
+		if errors.Is(err, io.EOF) { break }
+		if err != nil { t.Fatal(err) }
+		seen++
+		rel, err := filepath.Rel("src", filepath.FromSlash(e.RelPath()))
+		if err != nil { t.Fatal(err) }
+		content, ok := e.Verify()
+		if !ok { t.Errorf("%s: content does not match #sha256", rel) }
+		if want := files[filepath.ToSlash(rel)]; string(content) != want {
+			t.Errorf("%s: read back %q, want %q", rel, content, want)
+		}
+	}
+	if seen != len(files) { t.Fatalf("read %d blocks, want %d", seen, len(files)) }
+
+	dest := filepath.Join(dir, "restored")
+	results, err := Restore(out, dest, RestoreOptions{})
+	if err != nil { t.Fatal(err) }
+	for _, res := range results {
+		if res.Status != RestoreNew || res.Mismatch { t.Errorf("%s: restore status %s (%s)", res.RelPath, res.Status, res.Reason) }
+	}
+	for rel, want := range files {
+		got, err := os.ReadFile(filepath.Join(dest, "src", rel))
+		if err != nil { t.Fatal(err) }
+		if string(got) != want { t.Errorf("%s: restored %q, want %q", rel, got, want) }
+	}
+}
@@ -0,0 +1,37 @@
+package codedump
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Replacement is one literal find/replace rule of Config.Replace.
+type Replacement struct {
+	Old, New string
+}
+
+// ParseReplacements parses a comma-separated list of old=new rules, such as
+// "oldco.com=example.com,SecretCorp=ACME". The new text may be empty.
+func ParseReplacements(s string) ([]Replacement, error) {
+	var out []Replacement
+	for _, rule := range SplitClean(s) {
+		old, repl, ok := strings.Cut(rule, "=")
+		if !ok || old == "" { return nil, fmt.Errorf("replace: invalid rule %q (want old=new)", rule) }
+		out = append(out, Replacement{old, repl})
+	}
+	return out, nil
+}
+
+// ApplyReplacements applies the rules to src in order and reports how many
+// replacements were made in total.
+func ApplyReplacements(src []byte, rules []Replacement) ([]byte, int) {
+	n := 0
+	for _, r := range rules {
+		if k := bytes.Count(src, []byte(r.Old)); k > 0 {
+			src = bytes.ReplaceAll(src, []byte(r.Old), []byte(r.New))
+			n += k
+		}
+	}
+	return src, n
+}
@@ -0,0 +1,189 @@
+package codedump
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RestoreStatus describes what Restore does (or would do) with one file block.
+type RestoreStatus string
+
+const (
+	RestoreNew       RestoreStatus = "new"       // destination file does not exist
+	RestoreChanged   RestoreStatus = "changed"   // destination exists with a different hash
+	RestoreIdentical RestoreStatus = "identical" // destination already matches the recorded hash
+	RestoreSkipped   RestoreStatus = "skipped"   // block cannot be restored, see Reason
+)
+
+// RestoreResult is the outcome for one file block.
+type RestoreResult struct {
+	RelPath  string
+	Dest     string
+	Status   RestoreStatus
+	Reason   string
+	Mismatch bool // content did not match the recorded #sha256
+}
+
+// Values for RestoreOptions.OnMismatch.
+const (
+	MismatchError = "error" // stop with an error (default)
+	MismatchWarn  = "warn"  // restore the content anyway, flagging the result
+	MismatchSkip  = "skip"  // leave the file unwritten
+)
+
+// RestoreOptions tunes Restore.
+type RestoreOptions struct {
+	DryRun      bool   // report statuses without writing anything
+	OnMismatch  string // what to do when a block's content does not match its #sha256 ("" = MismatchError)
+	AllowParent bool   // write "../" paths outside the destination, as relative-to-out dumps may record
+}
+
+// Restore recreates the files recorded in a text dump under destDir, using each
+// block's #rel_path. New and changed files are written; identical ones are left
+// alone. With DryRun nothing is written.
+//
+// Each block's content is checked against its #sha256 unless the dump marks it
+// #transformed; opts.OnMismatch decides what happens to blocks that fail.
+// A #duplicate_of block is written with the content of the block it names.
+//
+// An empty destDir means the dump's own directory for dumps written with
+// paths=relative-to-out, and the current directory otherwise. Blocks whose path
+// climbs out of the destination with "../" are skipped unless opts.AllowParent
+// is set: the dump's header alone is never trusted to write outside it.
+//
+// A dump made with pkg=false lacks the stripped package lines, so restored Go
+// files differ from the originals; use pkg=true for dumps meant to be restored.
+func Restore(dumpPath, destDir string, opts RestoreOptions) ([]RestoreResult, error) {
+	switch opts.OnMismatch {
+	case "", MismatchError, MismatchWarn, MismatchSkip:
+	default: return nil, fmt.Errorf("on-mismatch: unknown mode %q (want error, warn or skip)", opts.OnMismatch)
+	}
+	f, err := os.Open(dumpPath)
+	if err != nil { return nil, err }
+	defer f.Close()
+
+	var out []RestoreResult
+	destAbs := ""
+	contents := map[string][]byte{} // rel path -> content, for #duplicate_of blocks
+	r := NewDumpReader(f)
+	for {
+		e, err := r.Next()
+		if errors.Is(err, io.EOF) { break }
+		if err != nil { return out, err }
+		if destAbs == "" {
+			if destDir == "" { destDir = restoreBase(dumpPath, r.Header) }
+			if destAbs, err = filepath.Abs(destDir); err != nil { return out, err }
+		}
+		if e.Meta["blame"] == "true" {
+			e.Content = StripBlame(e.Content)
+		}
+		if e.Meta["uncovered_lines"] != "" {
+			e.Content = StripCoverageMarks(e.Content)
+		}
+		if first := e.Meta["duplicate_of"]; first != "" {
+			if data, ok := contents[first]; ok {
+				e.Content = data
+				delete(e.Meta, "duplicate_of")
+			}
+		} else if e.HasContent() {
+			contents[e.RelPath()] = e.Content
+		}
+		res := planRestore(destAbs, e, opts.AllowParent)
+		content := e.Content
+		if res.Status != RestoreSkipped && !e.Transformed() {
+			var ok bool
+			if content, ok = e.Verify(); !ok {
+				res.Mismatch = true
+				switch opts.OnMismatch {
+				case MismatchWarn:
+					res.Reason = "sha256 mismatch"
+				case MismatchSkip:
+					res.Status, res.Reason = RestoreSkipped, "sha256 mismatch"
+				default:
+					return out, fmt.Errorf("%s: content does not match #sha256", res.RelPath)
+				}
+			}
+		}
+		if !opts.DryRun && (res.Status == RestoreNew || res.Status == RestoreChanged) {
+			if err := os.MkdirAll(filepath.Dir(res.Dest), 0o755); err != nil { return out, err }
+			if err := os.WriteFile(res.Dest, content, 0o644); err != nil { return out, err }
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
+
+// ErrSHA256Mismatch is reported by Unpack when written files did not match
+// their #sha256.
+var ErrSHA256Mismatch = errors.New("content does not match #sha256")
+
+// Unpack recreates every file of a text dump under destDir and returns how many
+// it wrote. It is Restore with OnMismatch set to MismatchWarn: files whose
+// content does not match their #sha256 are written all the same, and the
+// returned error then wraps ErrSHA256Mismatch and names them. Callers that
+// treat mismatches as warnings can check for it with errors.Is.
+func Unpack(dumpPath, destDir string) (int, error) {
+	results, err := Restore(dumpPath, destDir, RestoreOptions{OnMismatch: MismatchWarn})
+	n := 0
+	var bad []string
+	for _, r := range results {
+		if r.Status == RestoreNew || r.Status == RestoreChanged { n++ }
+		if r.Mismatch { bad = append(bad, r.RelPath) }
+	}
+	if err != nil { return n, err }
+	if len(bad) > 0 {
+		return n, fmt.Errorf("%w: %s", ErrSHA256Mismatch, strings.Join(bad, ", "))
+	}
+	return n, nil
+}
+
+// restoreBase picks the default destination for a dump from its #paths header.
+func restoreBase(dumpPath string, header map[string]string) string {
+	if header["paths"] == PathsRelativeToOut { return filepath.Dir(dumpPath) }
+	return "."
+}
+
+// planRestore decides the status of one block against the destination tree.
+// allowParent permits "../" paths, which a relative-to-out dump restored next to
+// itself may legitimately record; the caller has to ask for it.
+func planRestore(destAbs string, e *Entry, allowParent bool) RestoreResult {
+	res := RestoreResult{RelPath: e.RelPath()}
+	rel := filepath.FromSlash(e.RelPath())
+	switch {
+	case rel == "":
+		res.Status, res.Reason = RestoreSkipped, "missing #rel_path"
+		return res
+	case filepath.IsAbs(rel) || (!allowParent && (rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)))):
+		res.Status, res.Reason = RestoreSkipped, "path escapes destination"
+		return res
+	case !e.HasContent():
+		res.Status, res.Reason = RestoreSkipped, "no content (similar_to "+e.Meta["similar_to"]+")"
+		if e.Meta["duplicate_of"] != "" { res.Reason = "no content (duplicate_of " + e.Meta["duplicate_of"] + ")" }
+		return res
+	case e.Meta["excerpt"] == "true":
+		res.Status, res.Reason = RestoreSkipped, "excerpt only"
+		return res
+	case e.Meta["binary"] == "true":
+		res.Status, res.Reason = RestoreSkipped, "binary preview only"
+		return res
+	}
+	res.Dest = filepath.Join(destAbs, rel)
+	data, err := os.ReadFile(res.Dest)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		res.Status = RestoreNew
+	case err != nil:
+		res.Status, res.Reason = RestoreSkipped, err.Error()
+	default:
+		if sha256Hex(data) == e.SHA256() {
+			res.Status = RestoreIdentical
+		} else {
+			res.Status = RestoreChanged
+		}
+	}
+	return res
+}
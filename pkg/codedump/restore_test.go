@@ -0,0 +1,55 @@
+package codedump
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeDump writes a text dump with header fields h and files to path.
+func writeDump(t *testing.T, path string, h []Field, files []File) {
+	t.Helper()
+	var b bytes.Buffer
+	f := textFormatter{}
+	if err := f.WriteHeader(&b, Header{Fields: h}); err != nil { t.Fatal(err) }
+	for _, file := range files {
+		if err := f.WriteFile(&b, file); err != nil { t.Fatal(err) }
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil { t.Fatal(err) }
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil { t.Fatal(err) }
+}
+
+// textFile returns the block of a file rel holding content.
+func textFile(rel, content string) File {
+	return File{Rel: rel, Size: int64(len(content)), SHA256: sha256Hex([]byte(content)), Content: []byte(content)}
+}
+
+func TestRestoreRelativeToOutCannotEscape(t *testing.T) {
+	root := t.TempDir()
+	dumpPath := filepath.Join(root, "a", "b", "dump.txt")
+	writeDump(t, dumpPath, []Field{{"paths", PathsRelativeToOut}}, []File{
+		textFile("ok.txt", "inside\n"),
+		textFile("../../escaped.txt", "outside\n"),
+	})
+	escaped := filepath.Join(root, "escaped.txt")
+
+	for _, dest := range []string{"", filepath.Join(root, "dest")} {
+		results, err := Restore(dumpPath, dest, RestoreOptions{})
+		if err != nil { t.Fatal(err) }
+		for _, r := range results {
+			want := RestoreSkipped
+			if r.RelPath == "ok.txt" { want = RestoreNew }
+			if r.Status != want { t.Errorf("dest %q: %s is %s, want %s", dest, r.RelPath, r.Status, want) }
+		}
+		if _, err := os.Stat(escaped); !os.IsNotExist(err) { t.Fatalf("dest %q: %s was written", dest, escaped) }
+	}
+	if _, err := os.Stat(filepath.Join(root, "a", "b", "ok.txt")); err != nil { t.Errorf("ok.txt not restored next to the dump: %v", err) }
+	if _, err := os.Stat(filepath.Join(root, "dest", "ok.txt")); err != nil { t.Errorf("ok.txt not restored into dest: %v", err) }
+
+	// Only the caller can allow "../" paths.
+	if _, err := Restore(dumpPath, "", RestoreOptions{AllowParent: true}); err != nil { t.Fatal(err) }
+	if got, err := os.ReadFile(escaped); err != nil || string(got) != "outside\n" {
+		t.Errorf("with AllowParent, %s = %q, %v", escaped, got, err)
+	}
+}
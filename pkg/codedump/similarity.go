@@ -0,0 +1,87 @@
+package codedump
+
+import (
+	"bytes"
+	"hash/fnv"
+)
+
+const (
+	minHashSize   = 128 // number of hash functions in a signature
+	shingleTokens = 4   // words per shingle
+)
+
+// MinHash is a compact signature of a document's word shingles. The fraction of
+// equal positions between two signatures estimates their Jaccard similarity.
+type MinHash [minHashSize]uint64
+
+// NewMinHash computes the MinHash signature of content.
+func NewMinHash(content []byte) MinHash {
+	var sig MinHash
+	for i := range sig {
+		sig[i] = ^uint64(0)
+	}
+	words := bytes.Fields(content)
+	n := len(words) - shingleTokens + 1
+	if n < 1 { n = 1 }
+	for i := 0; i < n; i++ {
+		h := fnv.New64a()
+		for j := i; j < i+shingleTokens && j < len(words); j++ {
+			h.Write(words[j])
+			h.Write([]byte{0})
+		}
+		x := h.Sum64()
+		for k := range sig {
+			if v := mix64(x ^ uint64(k+1)*0x9e3779b97f4a7c15); v < sig[k] {
+				sig[k] = v
+			}
+		}
+	}
+	return sig
+}
+
+// Similarity estimates the Jaccard similarity (0..1) of the documents behind a and b.
+func (a MinHash) Similarity(b MinHash) float64 {
+	same := 0
+	for i := range a {
+		if a[i] == b[i] { same++ }
+	}
+	return float64(same) / minHashSize
+}
+
+// mix64 is the splitmix64 finalizer, used to derive independent hash functions.
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// similarityIndex groups near-identical documents around representatives.
+type similarityIndex struct {
+	threshold float64
+	reps      []similarRep
+}
+
+type similarRep struct {
+	rel string
+	sig MinHash
+}
+
+// match returns the most similar representative at or above the threshold. When
+// there is none, content becomes a new representative under rel.
+func (s *similarityIndex) match(rel string, content []byte) (string, float64, bool) {
+	sig := NewMinHash(content)
+	best, bestSim := -1, 0.0
+	for i, r := range s.reps {
+		if sim := sig.Similarity(r.sig); sim >= s.threshold && sim > bestSim {
+			best, bestSim = i, sim
+		}
+	}
+	if best < 0 {
+		s.reps = append(s.reps, similarRep{rel: rel, sig: sig})
+		return "", 0, false
+	}
+	return s.reps[best].rel, bestSim, true
+}
@@ -0,0 +1,37 @@
+package codedump
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits maps the suffixes ParseSize accepts to their multipliers. Units
+// are binary, so "1KB" and "1KiB" are both 1024 bytes.
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"K":   1 << 10,
+	"KB":  1 << 10,
+	"KIB": 1 << 10,
+	"M":   1 << 20,
+	"MB":  1 << 20,
+	"MIB": 1 << 20,
+	"G":   1 << 30,
+	"GB":  1 << 30,
+	"GIB": 1 << 30,
+}
+
+// ParseSize parses a byte count such as "1048576", "512KB", "5MB" or "1.5GiB".
+// Unit letters are case-insensitive.
+func ParseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	num, unit := s, ""
+	if i >= 0 { num, unit = s[:i], strings.ToUpper(strings.TrimSpace(s[i:])) }
+	mult, ok := sizeUnits[unit]
+	if !ok || num == "" { return 0, fmt.Errorf("invalid size %q", s) }
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v < 0 { return 0, fmt.Errorf("invalid size %q", s) }
+	return int64(v * float64(mult)), nil
+}
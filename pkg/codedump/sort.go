@@ -0,0 +1,93 @@
+package codedump
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Values for Config.Sort.
+const (
+	SortPath  = "path"
+	SortSize  = "size"
+	SortMtime = "mtime"
+	SortSHA   = "sha"
+)
+
+// checkSort reports an error for an unknown Config.Sort key.
+func checkSort(key string) error {
+	switch key {
+	case "", SortPath, SortSize, SortMtime, SortSHA: return nil
+	}
+	return fmt.Errorf("sort: unknown key %q (want path, size, mtime or sha)", key)
+}
+
+// sortItems orders items by key ("" = SortPath), descending when desc is set.
+// Items with equal keys stay in ascending path order either way.
+func sortItems(items []Item, key string, desc bool) {
+	cmp := func(a, b Item) int {
+		switch key {
+		case SortSize:
+			if a.size != b.size { return cmpInt(a.size < b.size) }
+		case SortMtime:
+			if !a.mtime.Equal(b.mtime) { return cmpInt(a.mtime.Before(b.mtime)) }
+		case SortSHA:
+			if a.sha != b.sha { return cmpInt(a.sha < b.sha) }
+		}
+		return 0
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if d := cmp(items[i], items[j]); d != 0 { return (d < 0) != desc }
+		if key == "" || key == SortPath { return (items[i].rel < items[j].rel) != desc }
+		return items[i].rel < items[j].rel
+	})
+}
+
+// cmpInt turns a "less" result of two unequal values into -1 or 1.
+func cmpInt(less bool) int {
+	if less { return -1 }
+	return 1
+}
+
+// Bucket names of Config.SizeGroup, smallest first.
+const (
+	SizeSmall  = "small"
+	SizeMedium = "medium"
+	SizeLarge  = "large"
+)
+
+// Default bucket thresholds of Config.SizeGroup.
+const (
+	DefaultSizeGroupSmall = 4 << 10
+	DefaultSizeGroupLarge = 32 << 10
+)
+
+// sizeGroups assigns each item to a SizeGroup bucket: SizeSmall below small
+// bytes, SizeLarge from large bytes up and SizeMedium in between. Items are
+// reordered bucket by bucket, smallest first unless desc is set, and by path
+// within a bucket. It returns the bucket of each item by absolute path, and
+// the section title of each bucket.
+func sizeGroups(items []Item, small, large int64, desc bool) (map[string]string, map[string]string, error) {
+	if small <= 0 { small = DefaultSizeGroupSmall }
+	if large <= 0 { large = DefaultSizeGroupLarge }
+	if small > large { return nil, nil, fmt.Errorf("size_group_small (%d) is above size_group_large (%d)", small, large) }
+	rank := map[string]int{SizeSmall: 0, SizeMedium: 1, SizeLarge: 2}
+	groups := make(map[string]string, len(items))
+	for _, it := range items {
+		switch {
+		case it.size < small: groups[it.abs] = SizeSmall
+		case it.size >= large: groups[it.abs] = SizeLarge
+		default: groups[it.abs] = SizeMedium
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		ri, rj := rank[groups[items[i].abs]], rank[groups[items[j].abs]]
+		if ri != rj { return (ri < rj) != desc }
+		return items[i].rel < items[j].rel
+	})
+	titles := map[string]string{
+		SizeSmall:  fmt.Sprintf("SIZE: %s (under %d bytes)", SizeSmall, small),
+		SizeMedium: fmt.Sprintf("SIZE: %s (%d to %d bytes)", SizeMedium, small, large-1),
+		SizeLarge:  fmt.Sprintf("SIZE: %s (%d bytes or more)", SizeLarge, large),
+	}
+	return groups, titles, nil
+}
@@ -0,0 +1,88 @@
+package codedump
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Header strip modes for Config.StripModes. They apply only when Pkg is false.
+const (
+	StripGoPackage = "go-package" // the first "package" line; the default for StripExts
+	StripShebang   = "shebang"    // a leading "#!" interpreter line
+	StripLicense   = "license"    // the leading comment block, such as a license header
+	StripNone      = "none"       // nothing; the default for other files
+)
+
+// checkStripMode returns an error unless mode is a known strip mode.
+func checkStripMode(mode string) error {
+	switch mode {
+	case StripGoPackage, StripShebang, StripLicense, StripNone: return nil
+	}
+	return fmt.Errorf("unknown strip mode %q (want %s, %s, %s or %s)", mode, StripGoPackage, StripShebang, StripLicense, StripNone)
+}
+
+// stripMode returns the strip mode for path: the StripModes entry for its
+// extension, else StripGoPackage for StripExts, else StripNone.
+func (c Config) stripMode(path string) string {
+	if m, ok := c.StripModes[strings.ToLower(filepath.Ext(path))]; ok { return m }
+	if hasExt(path, c.StripExts) { return StripGoPackage }
+	return StripNone
+}
+
+// stripHeader applies mode to src. StripLicense needs the file's comment
+// style; without a known one, src is returned as is.
+func stripHeader(mode string, cs CommentStyle, known bool, src []byte) []byte {
+	switch mode {
+	case StripGoPackage: return StripPackageLine(src)
+	case StripShebang: return StripShebangLine(src)
+	case StripLicense:
+		if known { return stripLeadingComment(cs, src) }
+	}
+	return src
+}
+
+// StripShebangLine removes a leading "#!" line.
+func StripShebangLine(src []byte) []byte {
+	if !bytes.HasPrefix(src, []byte("#!")) { return src }
+	if i := bytes.IndexByte(src, '\n'); i >= 0 { return src[i+1:] }
+	return nil
+}
+
+// stripLeadingComment removes the first comment block of src, along with the
+// blank lines around it, when nothing but blank lines and a shebang comes
+// before it. The shebang is kept, and so are Go directives such as
+// "//go:build": the block ends before the first one.
+func stripLeadingComment(cs CommentStyle, src []byte) []byte {
+	lines := bytes.Split(src, []byte("\n"))
+	keep := 0
+	if len(lines) > 0 && bytes.HasPrefix(lines[0], []byte("#!")) { keep = 1 }
+	blank := func(i int) bool { return len(bytes.TrimSpace(lines[i])) == 0 }
+	i := keep
+	for i < len(lines)-1 && blank(i) { i++ }
+	end := i
+	if p := cs.lineCommentPrefix(lines[i]); p != "" {
+		for end < len(lines) && cs.lineCommentPrefix(lines[end]) != "" && !isGoDirective(lines[end]) { end++ }
+		if end == i { return src }
+	} else if t := bytes.TrimSpace(lines[i]); cs.BlockStart != "" && bytes.HasPrefix(t, []byte(cs.BlockStart)) {
+		for end < len(lines) {
+			rest := lines[end]
+			if end == i { rest = t[len(cs.BlockStart):] }
+			end++
+			if bytes.Contains(rest, []byte(cs.BlockEnd)) { break }
+		}
+	} else {
+		return src
+	}
+	for end < len(lines)-1 && blank(end) { end++ }
+	out := append(lines[:keep:keep], lines[end:]...)
+	return bytes.Join(out, []byte("\n"))
+}
+
+// isGoDirective reports whether ln is a Go build constraint or "//go:"
+// directive, which must survive header stripping.
+func isGoDirective(ln []byte) bool {
+	t := bytes.TrimSpace(ln)
+	return bytes.HasPrefix(t, []byte("//go:")) || bytes.HasPrefix(t, []byte("// +build"))
+}
@@ -0,0 +1,81 @@
+package codedump
+
+import "testing"
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name, path, src, want string
+	}{
+		{"shell comments", "x.sh",
+			"#!/bin/sh\n# setup\necho hi # greet\n",
+			"#!/bin/sh\necho hi\n"},
+		{"shell $#", "x.sh",
+			"if [ $# -eq 0 ]; then exit 1; fi\n",
+			"if [ $# -eq 0 ]; then exit 1; fi\n"},
+		{"shell ${#arr[@]}", "x.bash",
+			"n=${#arr[@]} # count\necho ${x#prefix}\n",
+			"n=${#arr[@]}\necho ${x#prefix}\n"},
+		{"shell quoted", "x.sh",
+			"echo \"a # b\" 'c # d'\n",
+			"echo \"a # b\" 'c # d'\n"},
+		{"yaml", "x.yaml",
+			"# config\nurl: http://h/a#frag\ncolor: \"#fff\" # quoted\nname: a#b\n  # indented\n",
+			"url: http://h/a#frag\ncolor: \"#fff\"\nname: a#b\n"},
+		{"yaml bare hash value", "x.yml",
+			"color: #fff\n",
+			"color:\n"},
+		{"python", "x.py",
+			"x = 1  # one\ns = \"# not\"\n",
+			"x = 1\ns = \"# not\"\n"},
+		{"python triple quotes", "x.py",
+			"def f():\n    \"\"\"Doc.\n\n    # not a comment\n    \"\"\"\n    return '''a\n# kept'''  # gone\n",
+			"def f():\n    \"\"\"Doc.\n\n    # not a comment\n    \"\"\"\n    return '''a\n# kept'''\n"},
+		{"python empty strings", "x.py",
+			"a = '' # c\nb = \"\" # d\n",
+			"a = ''\nb = \"\"\n"},
+		{"c style", "x.js",
+			"/* head */\nlet s = \"// no\"; // yes\nlet t = a/b;\n",
+			"let s = \"// no\";\nlet t = a/b;\n"},
+		{"ruby still strips unspaced", "x.rb",
+			"x = 1#c\n",
+			"x = 1\n"},
+	}
+	for _, tt := range tests {
+		cs, ok := commentStyleFor(tt.path)
+		if !ok { t.Fatalf("%s: no comment style for %s", tt.name, tt.path) }
+		if got := string(StripComments(cs, []byte(tt.src))); got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStripLicenseKeepsGoDirectives(t *testing.T) {
+	tests := []struct {
+		name, src, want string
+	}{
+		{"license only",
+			"// Copyright 2024 Example.\n// Licensed under MIT.\n\npackage p\n",
+			"package p\n"},
+		{"license then build constraint",
+			"// Copyright 2024 Example.\n\n//go:build linux && amd64\n// +build linux,amd64\n\npackage p\n",
+			"//go:build linux && amd64\n// +build linux,amd64\n\npackage p\n"},
+		{"build constraint right after the license",
+			"// Copyright 2024 Example.\n//go:build ignore\n\npackage main\n",
+			"//go:build ignore\n\npackage main\n"},
+		{"build constraint first",
+			"//go:build tools\n\n// Copyright 2024 Example.\n\npackage tools\n",
+			"//go:build tools\n\n// Copyright 2024 Example.\n\npackage tools\n"},
+		{"go:generate first",
+			"//go:generate stringer -type=Kind\n\npackage p\n",
+			"//go:generate stringer -type=Kind\n\npackage p\n"},
+		{"block license",
+			"/*\n * Copyright 2024 Example.\n */\n\n//go:build linux\n\npackage p\n",
+			"//go:build linux\n\npackage p\n"},
+	}
+	cs, _ := commentStyleFor("x.go")
+	for _, tt := range tests {
+		if got := string(stripHeader(StripLicense, cs, true, []byte(tt.src))); got != tt.want {
+			t.Errorf("%s:\ngot  %q\nwant %q", tt.name, got, tt.want)
+		}
+	}
+}
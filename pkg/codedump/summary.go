@@ -0,0 +1,40 @@
+package codedump
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Summary is the JSON sidecar describing what a dump contains and what was left out.
+type Summary struct {
+	Files        int            `json:"files"`
+	TotalBytes   int64          `json:"total_bytes"`
+	GeneratedAt  string         `json:"generated_at"`
+	PerExtension map[string]int `json:"per_extension"`
+	Skipped      map[string]int `json:"skipped"` // skip reason -> count
+}
+
+// newSummary starts a summary and hooks c.OnSkip to tally skip reasons,
+// still forwarding to any OnSkip the caller installed.
+func newSummary(c *Config) *Summary {
+	s := &Summary{Skipped: map[string]int{}}
+	prev := c.OnSkip
+	c.OnSkip = func(path, reason string) {
+		s.Skipped[reason]++
+		if prev != nil { prev(path, reason) }
+	}
+	return s
+}
+
+// write fills in the per-file totals and writes the sidecar to path.
+func (s *Summary) write(fsys Filesystem, path string, generated time.Time, items []Item) error {
+	s.Files = len(items)
+	s.GeneratedAt = generated.Format(time.RFC3339)
+	for _, it := range items {
+		s.TotalBytes += it.size
+	}
+	s.PerExtension = extCounts(items)
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil { return err }
+	return writeFile(fsys, path, append(b, '\n'))
+}
@@ -0,0 +1,62 @@
+package codedump
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultTodoKeywords are the markers collected by the TODO index.
+const DefaultTodoKeywords = "TODO,FIXME,XXX,HACK"
+
+// TodoEntry is one tech-debt marker found in a dumped file.
+type TodoEntry struct {
+	Path    string
+	Line    int // 1-based
+	Keyword string
+	Text    string // the trimmed source line
+}
+
+// FindTodos scans items for the given comma-separated keywords (whole words,
+// case-sensitive) and returns the hits sorted by path and line.
+func FindTodos(items []Item, keywords string) ([]TodoEntry, error) {
+	if keywords == "" { keywords = DefaultTodoKeywords }
+	var alts []string
+	for _, k := range SplitClean(keywords) {
+		alts = append(alts, regexp.QuoteMeta(k))
+	}
+	re, err := regexp.Compile(`\b(` + strings.Join(alts, "|") + `)\b`)
+	if err != nil { return nil, err }
+
+	var out []TodoEntry
+	for _, it := range items {
+		data, err := it.read()
+		if err != nil { return nil, err }
+		sc := bufio.NewScanner(bytes.NewReader(data))
+		sc.Buffer(nil, 16<<20)
+		for n := 1; sc.Scan(); n++ {
+			if m := re.FindString(sc.Text()); m != "" {
+				out = append(out, TodoEntry{Path: it.rel, Line: n, Keyword: m, Text: strings.TrimSpace(sc.Text())})
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Path != out[j].Path { return out[i].Path < out[j].Path }
+		return out[i].Line < out[j].Line
+	})
+	return out, nil
+}
+
+// writeTodoIndex writes one "path:line: KEYWORD: text" line per marker.
+func writeTodoIndex(fsys Filesystem, path string, items []Item, keywords string) error {
+	todos, err := FindTodos(items, keywords)
+	if err != nil { return err }
+	var buf bytes.Buffer
+	for _, t := range todos {
+		fmt.Fprintf(&buf, "%s:%d: %s: %s\n", t.Path, t.Line, t.Keyword, t.Text)
+	}
+	return writeFile(fsys, path, buf.Bytes())
+}
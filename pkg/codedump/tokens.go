@@ -0,0 +1,23 @@
+package codedump
+
+import "unicode"
+
+// EstimateTokens roughly estimates how many LLM tokens content takes: every
+// run of letters and digits counts one token per four characters (rounded
+// up), every punctuation or symbol character counts one, and whitespace is
+// free. Real tokenizers differ, but it tracks them well enough for budgets.
+func EstimateTokens(content []byte) int {
+	n, run := 0, 0
+	for _, r := range string(content) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			run++
+			continue
+		case !unicode.IsSpace(r):
+			n++
+		}
+		n += (run + 3) / 4
+		run = 0
+	}
+	return n + (run+3)/4
+}
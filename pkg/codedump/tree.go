@@ -0,0 +1,78 @@
+package codedump
+
+import (
+	"sort"
+	"strings"
+)
+
+// treeNode is a directory or file in RenderTree.
+type treeNode struct {
+	name     string
+	children map[string]*treeNode // nil for files
+}
+
+// RenderTree draws the items' relative paths as an ASCII tree, directories
+// first at each level and marked with a trailing "/". The directories all
+// paths share head the tree ("." if there are none), like:
+//
+//	models/
+//	├── sub/
+//	│   └── b.go
+//	└── a.go
+func RenderTree(items []Item) string {
+	root := &treeNode{children: map[string]*treeNode{}}
+	for _, it := range items {
+		parts := strings.Split(it.rel, "/")
+		n := root
+		for i, p := range parts {
+			child := n.children[p]
+			if child == nil {
+				child = &treeNode{name: p}
+				if i < len(parts)-1 { child.children = map[string]*treeNode{} }
+				n.children[p] = child
+			}
+			n = child
+		}
+	}
+	var b strings.Builder
+	// Collapse the common leading directories into the first line.
+	prefix := ""
+	for len(root.children) == 1 {
+		only := root.children[onlyKey(root.children)]
+		if only.children == nil { break }
+		prefix += only.name + "/"
+		root = only
+	}
+	if prefix == "" { prefix = "." }
+	b.WriteString(prefix + "\n")
+	renderTreeLevel(&b, root, "")
+	return b.String()
+}
+
+// onlyKey returns the key of a one-element map.
+func onlyKey(m map[string]*treeNode) string {
+	for k := range m {
+		return k
+	}
+	return ""
+}
+
+// renderTreeLevel writes n's children, each line prefixed by indent.
+func renderTreeLevel(b *strings.Builder, n *treeNode, indent string) {
+	kids := make([]*treeNode, 0, len(n.children))
+	for _, k := range n.children {
+		kids = append(kids, k)
+	}
+	sort.Slice(kids, func(i, j int) bool {
+		if (kids[i].children != nil) != (kids[j].children != nil) { return kids[i].children != nil }
+		return kids[i].name < kids[j].name
+	})
+	for i, k := range kids {
+		branch, next := "├── ", "│   "
+		if i == len(kids)-1 { branch, next = "└── ", "    " }
+		name := k.name
+		if k.children != nil { name += "/" }
+		b.WriteString(indent + branch + name + "\n")
+		if k.children != nil { renderTreeLevel(b, k, indent+next) }
+	}
+}
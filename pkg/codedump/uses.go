@@ -0,0 +1,73 @@
+package codedump
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FileUses reports whether a file references target, which is either an import
+// path ("net/http"), a qualified symbol ("http.Handler") or a bare identifier
+// ("Handler"). Go files are parsed, so matches inside comments and strings do
+// not count, and the package of a qualified symbol must be imported: under
+// whatever name the file gives it (h.Handler after import h "net/http"), or
+// unqualified after a dot import. Other files (and Go files that fail to parse)
+// fall back to a plain substring search.
+func FileUses(path string, src []byte, target string) bool {
+	if !strings.HasSuffix(path, ".go") { return bytes.Contains(src, []byte(target)) }
+	f, err := parser.ParseFile(token.NewFileSet(), path, src, parser.SkipObjectResolution)
+	if err != nil { return bytes.Contains(src, []byte(target)) }
+
+	pkg, sym, qualified := strings.Cut(target, ".")
+	locals, dotted := map[string]bool{}, false // names the target's package goes by
+	for _, imp := range f.Imports {
+		p, err := strconv.Unquote(imp.Path.Value)
+		if err != nil { continue }
+		if p == target { return true }
+		if importName(p) != pkg { continue }
+		switch {
+		case imp.Name == nil: locals[pkg] = true
+		case imp.Name.Name == ".": dotted = true
+		case imp.Name.Name != "_": locals[imp.Name.Name] = true
+		}
+	}
+	if strings.Contains(target, "/") || (qualified && strings.Contains(sym, ".")) { return false }
+	if qualified && len(locals) == 0 && !dotted { return false }
+
+	found := false
+	sels := map[*ast.Ident]bool{} // selected names, which a dot import does not reach
+	ast.Inspect(f, func(n ast.Node) bool {
+		if found { return false }
+		switch x := n.(type) {
+		case *ast.SelectorExpr:
+			sels[x.Sel] = true
+			if id, ok := x.X.(*ast.Ident); ok && qualified && locals[id.Name] && x.Sel.Name == sym { found = true }
+		case *ast.Ident:
+			if !qualified && x.Name == target { found = true }
+			if qualified && dotted && !sels[x] && x.Name == sym { found = true }
+		}
+		return !found
+	})
+	return found
+}
+
+// importName returns the name a package at import path p is assumed to have,
+// as goimports does: the last element, skipping a major version ("/v2"), less
+// a "go-" prefix and anything from the first character that cannot be in an
+// identifier ("yaml" for gopkg.in/yaml.v3).
+func importName(p string) string {
+	name := path.Base(p)
+	if v, ok := strings.CutPrefix(name, "v"); ok && path.Dir(p) != "." {
+		if _, err := strconv.Atoi(v); err == nil { name = path.Base(path.Dir(p)) }
+	}
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.IndexFunc(name, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' }); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
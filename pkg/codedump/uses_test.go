@@ -0,0 +1,49 @@
+package codedump
+
+import "testing"
+
+func TestFileUses(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		target string
+		want   bool
+	}{
+		{"import path", `package p; import "net/http"`, "net/http", true},
+		{"other import path", `package p; import "net/url"`, "net/http", false},
+		{"qualified", `package p; import "net/http"; var _ http.Handler`, "http.Handler", true},
+		{"aliased", `package p; import h "net/http"; var _ h.Handler`, "http.Handler", true},
+		{"alias name is not the package", `package p; import h "net/http"; var _ h.Handler`, "h.Handler", false},
+		{"shadowed by alias", `package p; import http "example.com/fake"; var _ http.Handler`, "http.Handler", false},
+		{"not imported", `package p; var http struct{ Handler int }; var _ = http.Handler`, "http.Handler", false},
+		{"dot import", `package p; import . "net/http"; var _ Handler`, "http.Handler", true},
+		{"dot import, selected name", `package p; import . "net/http"; var _ = x.Handler`, "http.Handler", false},
+		{"blank import", `package p; import _ "net/http"; var _ = http.Handler`, "http.Handler", false},
+		{"major version", `package p; import "example.com/mux/v2"; var _ mux.Router`, "mux.Router", true},
+		{"not the version", `package p; import "example.com/mux/v2"; var _ v2.Router`, "v2.Router", false},
+		{"gopkg.in", `package p; import "gopkg.in/yaml.v3"; var _ yaml.Node`, "yaml.Node", true},
+		{"go- prefix", `package p; import "example.com/go-cmp"; var _ = cmp.Diff`, "cmp.Diff", true},
+		{"bare identifier", `package p; import "net/http"; var _ http.Handler`, "Handler", true},
+		{"comment only", "package p\n// http.Handler\n", "http.Handler", false},
+	}
+	for _, tt := range tests {
+		if got := FileUses("x.go", []byte(tt.src), tt.target); got != tt.want {
+			t.Errorf("%s: FileUses(%q) = %v, want %v", tt.name, tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestImportName(t *testing.T) {
+	for p, want := range map[string]string{
+		"net/http":               "http",
+		"fmt":                    "fmt",
+		"github.com/foo/bar/v2":  "bar",
+		"gopkg.in/yaml.v3":       "yaml",
+		"github.com/foo/go-bar":  "bar",
+		"github.com/foo/bar-go":  "bar",
+		"v2":                     "v2",
+		"github.com/foo/version": "version",
+	} {
+		if got := importName(p); got != want { t.Errorf("importName(%q) = %q, want %q", p, got, want) }
+	}
+}
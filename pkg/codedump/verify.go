@@ -0,0 +1,52 @@
+package codedump
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// Mismatch is a dump block whose content does not match its recorded hash.
+type Mismatch struct {
+	RelPath string
+	Field   string // the header field checked: "sha256" or "sha256_dumped"
+	Want    string // the recorded hash
+	Got     string // the hash of the content as found in the dump
+}
+
+// Verify re-reads a text dump and checks every block's content against its
+// recorded hash, returning the blocks that fail.
+//
+// Blocks holding the file as it is on disk are checked against #sha256, after
+// removing blame and coverage prefixes as Restore does. Blocks marked
+// #transformed (package line stripped, comments trimmed, ...) cannot match the
+// original file's #sha256, so they are checked against #sha256_dumped, the
+// hash of the content as dumped. Transformed blocks from dumps that predate
+// #sha256_dumped, and blocks without content, are not checked.
+func Verify(dumpPath string) ([]Mismatch, error) {
+	f, err := os.Open(dumpPath)
+	if err != nil { return nil, err }
+	defer f.Close()
+
+	var out []Mismatch
+	r := NewDumpReader(f)
+	for {
+		e, err := r.Next()
+		if errors.Is(err, io.EOF) { break }
+		if err != nil { return out, err }
+		if !e.HasContent() { continue }
+		field, content := "sha256", e.Content
+		if e.Transformed() {
+			field = "sha256_dumped"
+		} else {
+			if e.Meta["blame"] == "true" { content = StripBlame(content) }
+			if e.Meta["uncovered_lines"] != "" { content = StripCoverageMarks(content) }
+		}
+		want := e.Meta[field]
+		if want == "" { continue }
+		if _, ok := matchSHA256(content, want); !ok {
+			out = append(out, Mismatch{RelPath: e.RelPath(), Field: field, Want: want, Got: sha256Hex(content)})
+		}
+	}
+	return out, nil
+}
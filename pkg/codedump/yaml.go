@@ -0,0 +1,154 @@
+package codedump
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultYAMLName is the YAML variant of DefaultRCName, found by FindRC too.
+const DefaultYAMLName = ".codedump.yaml"
+
+// IsYAMLConfig reports whether path names a YAML config, by its extension.
+func IsYAMLConfig(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// ReadYAMLConfig populates the given Config from a YAML config file. It takes
+// the same keys as the RC format, and lists wherever the RC format takes
+// comma-separated values:
+//
+//	target: ./models
+//	ext: [.go, .proto]
+//	exclude:
+//	  - _test.go
+//	  - /vendor/
+//	comment:
+//	  .sql: "--"
+//
+// A nested mapping such as comment stands for the prefixed RC keys, here
+// comment.sql. Only this flat subset of YAML is understood. As with RC files,
+// unknown keys, invalid values and lines outside the subset are reported
+// through c.Warnf and otherwise skipped.
+func ReadYAMLConfig(path string, c *Config) error {
+	b, err := os.ReadFile(path)
+	if err != nil { return err }
+	for _, ln := range parseYAMLLines(b) {
+		if ln.malformed {
+			c.warnf("%s:%d: malformed line, want key: value or a list item", path, ln.n)
+			continue
+		}
+		if err := setRCKey(c, ln.key, ln.value); err != nil {
+			c.warnf("%s:%d: %v", path, ln.n, err)
+		}
+	}
+	return nil
+}
+
+// parseYAMLLines turns YAML config content into the key/value lines of the RC
+// format: lists are joined with commas and nested mappings become prefixed
+// keys. Each line keeps the number of the line its key is on. A key whose
+// block holds a malformed line is left out.
+func parseYAMLLines(b []byte) []rcLine {
+	var out []rcLine
+	var open *rcLine // top-level key without a value, collecting what follows
+	var items []string
+	nested, broken := false, false
+	flush := func() {
+		if open == nil { return }
+		if !nested && !broken {
+			open.value = strings.Join(items, ",")
+			out = append(out, *open)
+		}
+		open, items, nested, broken = nil, nil, false, false
+	}
+	// malformed reports line n. Inside an open key's block it drops that
+	// key, rather than applying the part of its list read so far.
+	malformed := func(n int) {
+		out = append(out, rcLine{n: n, malformed: true})
+		broken = open != nil
+	}
+	for i, raw := range strings.Split(string(b), "\n") {
+		n := i + 1
+		ln := strings.TrimRight(yamlStripComment(raw), " \t\r")
+		if strings.TrimSpace(ln) == "" || ln == "---" { continue }
+		body := strings.TrimLeft(ln, " ")
+		if strings.HasPrefix(body, "\t") || (body != ln && open == nil) {
+			malformed(n)
+			continue
+		}
+		if body == ln {
+			flush()
+			k, v, ok := strings.Cut(body, ":")
+			if !ok || strings.TrimSpace(k) == "" {
+				malformed(n)
+				continue
+			}
+			l := rcLine{n: n, key: strings.TrimSpace(k)}
+			v = strings.TrimSpace(v)
+			switch {
+			case v == "":
+				open = &l
+			case strings.HasPrefix(v, "["):
+				if !strings.HasSuffix(v, "]") {
+					malformed(n)
+					continue
+				}
+				var list []string
+				for _, it := range strings.Split(v[1:len(v)-1], ",") {
+					if it = strings.TrimSpace(it); it != "" { list = append(list, yamlUnquote(it)) }
+				}
+				l.value = strings.Join(list, ",")
+				out = append(out, l)
+			default:
+				l.value = yamlUnquote(v)
+				out = append(out, l)
+			}
+			continue
+		}
+		if item, ok := strings.CutPrefix(body, "-"); ok && !nested && (item == "" || item[0] == ' ') {
+			items = append(items, yamlUnquote(strings.TrimSpace(item)))
+			continue
+		}
+		k, v, ok := strings.Cut(body, ":")
+		if !ok || len(items) > 0 || strings.TrimSpace(k) == "" {
+			malformed(n)
+			continue
+		}
+		nested = true
+		child := strings.TrimPrefix(yamlUnquote(strings.TrimSpace(k)), ".")
+		out = append(out, rcLine{n: n, key: open.key + "." + child, value: yamlUnquote(strings.TrimSpace(v))})
+	}
+	flush()
+	return out
+}
+
+// yamlStripComment drops a "#" comment that starts the line or follows a
+// space, outside of quotes.
+func yamlStripComment(ln string) string {
+	var quote byte
+	for i := 0; i < len(ln); i++ {
+		switch c := ln[i]; {
+		case quote != 0:
+			if c == quote { quote = 0 }
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || ln[i-1] == ' ' || ln[i-1] == '\t'):
+			return ln[:i]
+		}
+	}
+	return ln
+}
+
+// yamlUnquote returns the value of a plain, single- or double-quoted scalar.
+func yamlUnquote(s string) string {
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		if u, err := strconv.Unquote(s); err == nil { return u }
+	}
+	if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package codedump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseYAMLLines(t *testing.T) {
+	tests := []struct {
+		name, src string
+		want      []rcLine
+	}{
+		{"scalars",
+			"---\ntarget: ./models\npkg: false # keep it off\n\n# a comment\nout: dump.txt\n",
+			[]rcLine{{n: 2, key: "target", value: "./models"}, {n: 3, key: "pkg", value: "false"}, {n: 6, key: "out", value: "dump.txt"}}},
+		{"flow list",
+			"ext: [.go, \".proto\", '.sql']\nempty: []\n",
+			[]rcLine{{n: 1, key: "ext", value: ".go,.proto,.sql"}, {n: 2, key: "empty", value: ""}}},
+		{"block list",
+			"exclude:\n  - _test.go\n  - \"**/vendor/\"   # any depth\n  -   /cmd/\ninclude:\n",
+			[]rcLine{{n: 1, key: "exclude", value: "_test.go,**/vendor/,/cmd/"}, {n: 5, key: "include", value: ""}}},
+		{"nested map",
+			"comment:\n  .sql: \"--\"\n  .lua: '-- | --[[ ]]'\nstrip_mode:\n    .py: shebang\nout: x.txt\n",
+			[]rcLine{{n: 2, key: "comment.sql", value: "--"}, {n: 3, key: "comment.lua", value: "-- | --[[ ]]"}, {n: 5, key: "strip_mode.py", value: "shebang"}, {n: 6, key: "out", value: "x.txt"}}},
+		{"quoted # and :",
+			"include: \"a#b\"\nsystem: 'Review: carefully # really'\nurl: \"http://h:80/#x\" # trailing\nplain: a#b\nsingle: 'it''s'\n",
+			[]rcLine{{n: 1, key: "include", value: "a#b"}, {n: 2, key: "system", value: "Review: carefully # really"}, {n: 3, key: "url", value: "http://h:80/#x"}, {n: 4, key: "plain", value: "a#b"}, {n: 5, key: "single", value: "it's"}}},
+		{"escapes in double quotes",
+			"system: \"line\\tone\"\n",
+			[]rcLine{{n: 1, key: "system", value: "line\tone"}}},
+		{"indented without a parent",
+			"  target: ./x\nout: y\n",
+			[]rcLine{{n: 1, malformed: true}, {n: 2, key: "out", value: "y"}}},
+		{"tab indentation",
+			"exclude:\n\t- a\nout: y\n",
+			[]rcLine{{n: 2, malformed: true}, {n: 3, key: "out", value: "y"}}},
+		{"mapping after list items",
+			"exclude:\n  - a\n  key: v\n",
+			[]rcLine{{n: 3, malformed: true}}},
+		{"unclosed flow list and missing colon",
+			"ext: [.go, .proto\njust text\n: v\n",
+			[]rcLine{{n: 1, malformed: true}, {n: 2, malformed: true}, {n: 3, malformed: true}}},
+	}
+	for _, tt := range tests {
+		if got := parseYAMLLines([]byte(tt.src)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", tt.name, got, tt.want)
+		}
+	}
+}